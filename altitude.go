@@ -0,0 +1,225 @@
+package uddf
+
+import (
+	"math"
+	"time"
+)
+
+// AltitudeOptions tune NoFlyTime and AltitudeExposure. Zero values select
+// the default given in brackets.
+type AltitudeOptions struct {
+	// Model is the parameter set the dive is replayed with [the first one of
+	// the document's DecoModel, or ZHL16C].
+	Model *Buehlmann
+	// GradientFactor is the supersaturation tolerated at altitude [the GF
+	// high of Model].
+	GradientFactor float64
+	// CabinAltitude is the altitude in metres the cabin of a commercial
+	// aircraft is pressurised to [2438, i.e. 8000 ft].
+	CabinAltitude float64
+	// MinimumNoFlyTime is a lower bound for the no-fly time in seconds, such
+	// as the 12 or 18 hours commonly recommended [0].
+	MinimumNoFlyTime float64
+	// Step is the resolution of the calculation in seconds [60].
+	Step float64
+}
+
+func (o AltitudeOptions) withDefaults() AltitudeOptions {
+	if o.GradientFactor <= 0 {
+		_, o.GradientFactor = o.Model.GradientFactors()
+	}
+	setDefault(&o.CabinAltitude, 2438)
+	setDefault(&o.Step, 60)
+	return o
+}
+
+// maxNoFlyTime caps no-fly times, after which the tissues are practically
+// desaturated.
+const maxNoFlyTime = 72 * 3600
+
+// Tissues returns the tissue state at the end of d, replaying its samples
+// from start, or from tissues saturated at the surface of the dive if start
+// is nil. Mixes follow the mix switches, air is breathed before the first
+// one. A dive without samples is taken to be spent at its greatest depth.
+func (u *UDDF) Tissues(d *Dive, params *Buehlmann, start *TissueState) *TissueState {
+	env := u.Environment(d)
+	state := NewTissueState(params, env.Surface())
+	if start != nil {
+		state = start.Clone()
+	}
+
+	var waypoints []Waypoint
+	if d.Samples != nil {
+		waypoints = d.Samples.Waypoints
+	}
+	if len(waypoints) == 0 {
+		info := d.InformationAfterDive
+		waypoints = []Waypoint{{}, {Depth: info.GreatestDepth}, {Depth: info.GreatestDepth, DiveTime: info.DiveDuration}, {DiveTime: info.DiveDuration}}
+		for _, t := range d.TankData {
+			for _, l := range t.Links {
+				if u.mix(l.Ref) != nil && waypoints[0].SwitchMix == nil {
+					waypoints[0].SwitchMix = &SwitchMix{Ref: l.Ref}
+				}
+			}
+		}
+	}
+
+	gas := Air
+	for i, w := range waypoints {
+		if i > 0 {
+			prev := waypoints[i-1]
+			state.Expose(env.Pressure(prev.Depth), env.Pressure(w.Depth), w.DiveTime-prev.DiveTime, gas)
+		}
+		if w.SwitchMix != nil {
+			if m := u.mix(w.SwitchMix.Ref); m != nil {
+				gas = m.Fractions()
+			}
+		}
+	}
+	return state
+}
+
+// NoFlyTime returns the time in seconds the diver has to spend at a surface
+// pressure of surface bar, breathing air, until the tissues tolerate the
+// cabin pressure of a commercial aircraft.
+func (s *TissueState) NoFlyTime(surface float64, opts AltitudeOptions) float64 {
+	opts = opts.withDefaults()
+	cabin := Environment{Altitude: opts.CabinAltitude}.Surface()
+	tolerated := func(interval float64) bool {
+		trial := s.Clone()
+		trial.Expose(surface, surface, interval, Air)
+		return trial.Ceiling(opts.GradientFactor) <= cabin
+	}
+
+	// Binary search over whole steps
+	low, high := 0, int(math.Ceil(maxNoFlyTime/opts.Step))
+	for low < high {
+		mid := (low + high) / 2
+		if tolerated(float64(mid) * opts.Step) {
+			high = mid
+		} else {
+			low = mid + 1
+		}
+	}
+	return math.Max(float64(low)*opts.Step, opts.MinimumNoFlyTime)
+}
+
+// AltitudeViolation is a moment the tissues do not tolerate the ambient
+// pressure of a trip to altitude.
+type AltitudeViolation struct {
+	Time     float64 // seconds after surfacing
+	Altitude float64 // metres
+	Pressure float64 // ambient pressure in bar
+	Ceiling  float64 // lowest ambient pressure tolerated in bar
+	Reason   string  // "way altitude", "altitude exposure" or "no-fly time"
+}
+
+// AltitudeExposure is the altitude exposure after a dive.
+type AltitudeExposure struct {
+	Tissues   *TissueState // on surfacing
+	NoFlyTime float64      // seconds
+	// RecordedNoFlyTime is InformationAfterDive.NoFlightTime, zero if not
+	// given.
+	RecordedNoFlyTime float64
+	Violations        []AltitudeViolation
+}
+
+// AltitudeExposure computes the no-fly time after d and checks the trip
+// described in SurfaceIntervalAfterDive. Way altitudes are followed from
+// surfacing, interpolating linearly between them; the tissues must tolerate
+// the ambient pressure all the way. A planned ExposureToAltitude must be
+// tolerated on arrival, after SurfaceIntervalBeforeAltitudeExposure or at
+// DateOfFlight. Commercial aircraft are pressurised to CabinAltitude, and a
+// flight before MinimumNoFlyTime is a violation. The tissues are replayed
+// from start, see Tissues.
+func (u *UDDF) AltitudeExposure(d *Dive, start *TissueState, opts AltitudeOptions) *AltitudeExposure {
+	if opts.Model == nil {
+		model := u.buehlmannFor(&Profile{})
+		opts.Model = &model
+	}
+	opts = opts.withDefaults()
+	env := u.Environment(d)
+	surface := env.Surface()
+
+	e := &AltitudeExposure{Tissues: u.Tissues(d, opts.Model, start)}
+	e.NoFlyTime = e.Tissues.NoFlyTime(surface, opts)
+	info := d.InformationAfterDive
+	if info.NoFlightTime != nil {
+		e.RecordedNoFlyTime = *info.NoFlightTime
+	}
+	trip := info.SurfaceIntervalAfterDive
+	if trip == nil {
+		return e
+	}
+
+	if n := len(trip.WayAltitudes); n > 0 {
+		state := e.Tissues.Clone()
+		altitude := func(at float64) float64 {
+			ws := trip.WayAltitudes
+			if at <= ws[0].WayTime {
+				if ws[0].WayTime <= 0 {
+					return ws[0].Value
+				}
+				return env.Altitude + (ws[0].Value-env.Altitude)*at/ws[0].WayTime
+			}
+			for i := 1; i < len(ws); i++ {
+				if at <= ws[i].WayTime {
+					a, b := ws[i-1], ws[i]
+					return a.Value + (b.Value-a.Value)*(at-a.WayTime)/(b.WayTime-a.WayTime)
+				}
+			}
+			return ws[len(ws)-1].Value
+		}
+		pressure := func(at float64) float64 {
+			return Environment{Altitude: altitude(at)}.Surface()
+		}
+
+		violating := false
+		end := trip.WayAltitudes[n-1].WayTime
+		for at := 0.0; at <= end; at += opts.Step {
+			if at > 0 {
+				state.Expose(pressure(at-opts.Step), pressure(at), opts.Step, Air)
+			}
+			ceiling := state.Ceiling(opts.GradientFactor)
+			if ceiling <= pressure(at) {
+				violating = false
+				continue
+			}
+			if !violating {
+				e.Violations = append(e.Violations, AltitudeViolation{Time: at, Altitude: altitude(at), Pressure: pressure(at), Ceiling: ceiling, Reason: "way altitude"})
+			}
+			violating = true
+		}
+	}
+
+	if x := trip.ExposureToAltitude; x != nil && x.AltitudeOfExposure != nil {
+		interval := -1.0
+		switch {
+		case x.SurfaceIntervalBeforeAltitudeExposure != nil:
+			interval = *x.SurfaceIntervalBeforeAltitudeExposure
+		case x.DateOfFlight != nil:
+			surfaced := u.DiveTime(d, nil).Add(time.Duration(info.DiveDuration * float64(time.Second)))
+			interval = x.DateOfFlight.DateTime.In(nil).Sub(surfaced).Seconds()
+		}
+		if interval >= 0 {
+			altitude := *x.AltitudeOfExposure
+			if x.Transportation == "commercial-aircraft" {
+				altitude = math.Min(altitude, opts.CabinAltitude)
+			}
+			state := e.Tissues.Clone()
+			state.Expose(surface, surface, interval, Air)
+			ceiling := state.Ceiling(opts.GradientFactor)
+			pressure := Environment{Altitude: altitude}.Surface()
+			v := AltitudeViolation{Time: interval, Altitude: altitude, Pressure: pressure, Ceiling: ceiling}
+			switch {
+			case ceiling > pressure:
+				v.Reason = "altitude exposure"
+				e.Violations = append(e.Violations, v)
+			case x.Transportation == "commercial-aircraft" && interval < opts.MinimumNoFlyTime:
+				v.Reason = "no-fly time"
+				e.Violations = append(e.Violations, v)
+			}
+		}
+	}
+	return e
+}
@@ -0,0 +1,83 @@
+package uddf
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAltitudeExposure(t *testing.T) {
+	params := Buehlmann{GradientFactorLow: ptr(0.3), GradientFactorHigh: ptr(0.85)}
+	air := Air.Mix("mix-air")
+	plan, err := PlanDive([]Segment{{Depth: 40, Duration: 25 * 60}}, []Mix{air}, params, PlanOptions{})
+	if err != nil {
+		t.Fatalf("failed to plan dive: %v", err)
+	}
+	u := buildDocument(t, func(b *Builder) {
+		b.AddMix("Air", 0.21, 0).
+			AddDive(func(d *DiveBuilder) {
+				d.At(time.Date(2024, 9, 1, 9, 0, 0, 0, time.UTC)).Duration(time.Duration(plan.Runtime) * time.Second)
+				d.Dive().Samples = &Samples{Waypoints: plan.Waypoints}
+			})
+	})
+	d := &u.ProfileData.RepetitionGroup[0].Dives[0]
+	opts := AltitudeOptions{GradientFactor: 0.85}
+
+	t.Run("no-fly time should grow with the dive", func(t *testing.T) {
+		e := u.AltitudeExposure(d, nil, opts)
+		if e.NoFlyTime < 1800 || e.NoFlyTime > 24*3600 {
+			t.Errorf("expected a no-fly time of more than half an hour, got %.1f h", e.NoFlyTime/3600)
+		}
+		saturated := NewTissueState(nil, seaLevelPressure)
+		if nf := saturated.NoFlyTime(seaLevelPressure, opts); nf != 0 {
+			t.Errorf("expected no no-fly time without a dive, got %v", nf)
+		}
+		if nf := saturated.NoFlyTime(seaLevelPressure, AltitudeOptions{MinimumNoFlyTime: 12 * 3600}); nf != 12*3600 {
+			t.Errorf("expected the minimum no-fly time, got %v", nf)
+		}
+		if tissues := u.Tissues(d, nil, nil); tissues.NoFlyTime(seaLevelPressure, AltitudeOptions{CabinAltitude: 1000, GradientFactor: 0.85}) >= e.NoFlyTime {
+			t.Error("expected a shorter no-fly time for a lower cabin altitude")
+		}
+	})
+
+	t.Run("mountain passes should be checked", func(t *testing.T) {
+		d.InformationAfterDive.SurfaceIntervalAfterDive = &SurfaceIntervalAfterDive{WayAltitudes: []WayAltitude{
+			{WayTime: 900, Value: 500}, {WayTime: 1800, Value: 2800}, {WayTime: 3600, Value: 600},
+		}}
+		defer func() { d.InformationAfterDive.SurfaceIntervalAfterDive = nil }()
+
+		e := u.AltitudeExposure(d, nil, opts)
+		if len(e.Violations) != 1 {
+			t.Fatalf("expected one violation, got %+v", e.Violations)
+		}
+		if v := e.Violations[0]; v.Reason != "way altitude" || v.Time <= 900 || v.Time > 1800 || v.Ceiling <= v.Pressure {
+			t.Errorf("unexpected violation %+v", v)
+		}
+
+		d.InformationAfterDive.SurfaceIntervalAfterDive.WayAltitudes[1].Value = 300
+		if e := u.AltitudeExposure(d, nil, opts); len(e.Violations) != 0 {
+			t.Errorf("expected no violation at low altitude, got %+v", e.Violations)
+		}
+	})
+
+	t.Run("flights should respect the no-fly time", func(t *testing.T) {
+		flight := &ExposureToAltitude{AltitudeOfExposure: ptr(11000.0), Transportation: "commercial-aircraft", SurfaceIntervalBeforeAltitudeExposure: ptr(1800.0)}
+		d.InformationAfterDive.SurfaceIntervalAfterDive = &SurfaceIntervalAfterDive{ExposureToAltitude: flight}
+		defer func() { d.InformationAfterDive.SurfaceIntervalAfterDive = nil }()
+
+		e := u.AltitudeExposure(d, nil, opts)
+		if len(e.Violations) != 1 || e.Violations[0].Reason != "altitude exposure" || e.Violations[0].Altitude != 2438 {
+			t.Errorf("expected the cabin altitude to be exceeded, got %+v", e.Violations)
+		}
+
+		flight.SurfaceIntervalBeforeAltitudeExposure = nil
+		flight.DateOfFlight = &Date{DateTime: Time(time.Date(2024, 9, 2, 9, 0, 0, 0, time.UTC))}
+		if e := u.AltitudeExposure(d, nil, opts); len(e.Violations) != 0 {
+			t.Errorf("expected a flight the next day to be fine, got %+v", e.Violations)
+		}
+		opts := opts
+		opts.MinimumNoFlyTime = 24 * 3600
+		if e := u.AltitudeExposure(d, nil, opts); len(e.Violations) != 1 || e.Violations[0].Reason != "no-fly time" {
+			t.Errorf("expected the minimum no-fly time to be violated, got %+v", e.Violations)
+		}
+	})
+}
@@ -0,0 +1,177 @@
+package uddf
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AnonymizePolicy controls Anonymize. Zero values select the defaults.
+type AnonymizePolicy struct {
+	// Key seeds the pseudonyms, the date shift and the coordinate jitter.
+	// Documents anonymized with the same key get the same pseudonym for the
+	// same person, the same shift and the same jitter for the same spot.
+	// Without a key a random one is used.
+	Key string
+	// KeepNames keeps the names of people instead of replacing them with
+	// pseudonyms.
+	KeepNames bool
+	// DateShift is the largest shift of dates and times [365 days]. All of
+	// them are shifted by the same whole number of days, at least one.
+	DateShift time.Duration
+	// KeepDates leaves dates and times unchanged.
+	KeepDates bool
+	// CoordinateJitter is the largest displacement of coordinates in
+	// degrees [0.01].
+	CoordinateJitter float64
+	// KeepCoordinates leaves coordinates unchanged.
+	KeepCoordinates bool
+}
+
+// Anonymize returns a copy of u without personal data, for sharing files for
+// research or bug reports:
+//
+//   - names of people are replaced with pseudonyms and the IDs of the owner
+//     and the buddies with IDs derived from them
+//   - birth dates and names, blood groups, heights, weights, sex, smoking
+//     habits, memberships and certificate numbers are removed
+//   - medical examinations, insurances, permits, addresses and contact
+//     details are removed
+//   - dates and times are shifted and coordinates jittered
+//
+// Free text such as notes is left as it is. The result is valid if u is.
+func Anonymize(u *UDDF, policy AnonymizePolicy) *UDDF {
+	if policy.Key == "" {
+		key := make([]byte, 32)
+		rand.Read(key)
+		policy.Key = string(key)
+	}
+	if policy.DateShift <= 0 {
+		policy.DateShift = 365 * 24 * time.Hour
+	}
+	if policy.CoordinateJitter <= 0 {
+		policy.CoordinateJitter = 0.01
+	}
+	a := anonymizer{policy: policy}
+
+	days := int(policy.DateShift / (24 * time.Hour))
+	shift := time.Duration(1+int(a.unit("date")*float64(max(days, 1)))) * 24 * time.Hour
+	if a.unit("date sign") < 0.5 {
+		shift = -shift
+	}
+
+	result := u.Clone()
+	renamed := map[string]string{}
+	Walk(result, Visitor{Node: func(n *Node) WalkAction {
+		switch v := n.Value.(type) {
+		case *Contact, *Address, *Medical, *DiveInsurances, *DivePermissions:
+			return Remove
+		case *Owner:
+			a.rename(renamed, &v.Id, "owner", v.Personal)
+		case *Buddy:
+			a.rename(renamed, &v.Id, "buddy", v.Personal)
+		case *Personal:
+			a.personal(v, n.Parent.Name)
+		case *Certification:
+			v.CertificateNumber = nil
+		case *Geography:
+			if !policy.KeepCoordinates && v.Latitude != nil && v.Longitude != nil {
+				a.jitter(v.Latitude, v.Longitude)
+			}
+		case *Time:
+			if t := time.Time(*v); !policy.KeepDates && !t.IsZero() {
+				*v = Time(t.Add(shift))
+			}
+		}
+		return Continue
+	}})
+
+	Walk(result, Visitor{Node: func(n *Node) WalkAction {
+		if ref, ok := n.Value.(*string); ok && referenceAttrs[n.Name] {
+			if newID, ok := renamed[*ref]; ok {
+				*ref = newID
+			}
+		}
+		return Continue
+	}})
+	return result
+}
+
+type anonymizer struct {
+	policy AnonymizePolicy
+}
+
+// sum returns the keyed hash of s.
+func (a anonymizer) sum(s string) []byte {
+	mac := hmac.New(sha256.New, []byte(a.policy.Key))
+	mac.Write([]byte(s))
+	return mac.Sum(nil)
+}
+
+// unit maps s to a number in [0, 1).
+func (a anonymizer) unit(s string) float64 {
+	return float64(binary.BigEndian.Uint64(a.sum(s))>>11) / (1 << 53)
+}
+
+// pseudonym returns a stable identifier for the person p, empty if p has
+// no name.
+func (a anonymizer) pseudonym(p Personal) string {
+	name := strings.ToLower(personalName(p))
+	if name == "" {
+		return ""
+	}
+	return hex.EncodeToString(a.sum("person " + name))[:8]
+}
+
+// rename replaces *id with one derived from the pseudonym of p, numbered if
+// several people share a name.
+func (a anonymizer) rename(renamed map[string]string, id *string, prefix string, p Personal) {
+	if a.policy.KeepNames || *id == "" {
+		return
+	}
+	pseudonym := a.pseudonym(p)
+	if pseudonym == "" {
+		pseudonym = hex.EncodeToString(a.sum("id " + *id))[:8]
+	}
+
+	taken := map[string]bool{}
+	for _, newID := range renamed {
+		taken[newID] = true
+	}
+	newID := prefix + "-" + pseudonym
+	for i := 2; taken[newID]; i++ {
+		newID = fmt.Sprintf("%s-%s-%d", prefix, pseudonym, i)
+	}
+	renamed[*id] = newID
+	*id = newID
+}
+
+// personal removes the personal data of p and replaces its name with a
+// pseudonym prefixed with the role, e.g. "Buddy 1a2b3c4d".
+func (a anonymizer) personal(p *Personal, role string) {
+	if !a.policy.KeepNames {
+		if pseudonym := a.pseudonym(*p); pseudonym != "" {
+			p.FirstName = ptr(strings.ToUpper(role[:1]) + role[1:])
+			p.LastName = ptr(pseudonym)
+		}
+		p.MiddleName, p.BirthName, p.Honorific = nil, nil, nil
+	}
+	p.BirthDate, p.BloodGroup, p.Height, p.Weight = nil, nil, nil, nil
+	p.Sex, p.Smoking, p.Membership = nil, nil, nil
+}
+
+// jitter moves a position by up to CoordinateJitter degrees, the same
+// position always by the same amount.
+func (a anonymizer) jitter(lat, lon *float64) {
+	spot := strconv.FormatFloat(*lat, 'f', 6, 64) + "," + strconv.FormatFloat(*lon, 'f', 6, 64)
+	j := a.policy.CoordinateJitter
+	*lat = math.Max(-90, math.Min(90, *lat+(2*a.unit("lat "+spot)-1)*j))
+	*lon = math.Mod(*lon+(2*a.unit("lon "+spot)-1)*j+540, 360) - 180
+}
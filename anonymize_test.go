@@ -0,0 +1,119 @@
+package uddf
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAnonymize(t *testing.T) {
+	start := time.Date(2023, 8, 1, 9, 0, 0, 0, time.UTC)
+	build := func(buddy string) *UDDF {
+		u := buildDocument(t, func(b *Builder) {
+			b.Generator("test", "1.0").
+				AddBuddy(buddy, "Doe").
+				AddMix("Air", 0.21, 0).
+				AddSite("Blue Hole", 17.3, -87.5).
+				AddDive(func(d *DiveBuilder) {
+					d.At(start).Site("Blue Hole").Buddy(buddy+" Doe").Tank("Air", 0.012, 200e5, 60e5)
+					d.Sample(0, 0).Sample(20*time.Minute, 18).Sample(45*time.Minute, 0)
+				})
+		})
+		owner := &u.Diver.Owner
+		owner.Personal.BirthDate = &Date{DateTime: Time(time.Date(1980, 5, 4, 0, 0, 0, 0, time.UTC))}
+		owner.Personal.BloodGroup = ptr("0+")
+		owner.Personal.Weight = ptr(70.0)
+		owner.Contact = &Contact{Emails: []string{"jane@example.com"}}
+		owner.Medical = &Medical{}
+		owner.DiveInsurances = &DiveInsurances{Insurances: []Insurance{{Name: "DAN"}}}
+		u.Diver.Buddies[0].Certification = &Certification{Level: "Rescue", CertificateNumber: ptr("R-1")}
+		return u
+	}
+
+	u := build("John")
+	a := Anonymize(u, AnonymizePolicy{Key: "secret"})
+
+	t.Run("should remove personal data and keep the document valid", func(t *testing.T) {
+		if err := a.Validate(); err != nil {
+			t.Fatalf("expected valid document, got %v", err)
+		}
+		if issues := a.Check(); HasErrors(issues) {
+			t.Fatalf("expected no errors, got %v", issues)
+		}
+
+		owner := a.Diver.Owner
+		if *owner.Personal.FirstName != "Owner" || *owner.Personal.LastName == "Diver" {
+			t.Errorf("expected a pseudonym, got %s", personalName(owner.Personal))
+		}
+		if owner.Personal.BirthDate != nil || owner.Personal.BloodGroup != nil || owner.Personal.Weight != nil {
+			t.Errorf("expected personal data to be removed, got %+v", owner.Personal)
+		}
+		if owner.Contact != nil || owner.Medical != nil || owner.DiveInsurances != nil {
+			t.Error("expected contact, medical and insurance data to be removed")
+		}
+		if a.Diver.Buddies[0].Certification.CertificateNumber != nil {
+			t.Error("expected the certificate number to be removed")
+		}
+		if u.Diver.Owner.Contact == nil || *u.Diver.Owner.Personal.LastName != "Diver" {
+			t.Error("expected the original document to be unchanged")
+		}
+	})
+
+	t.Run("buddy IDs and references should be renamed", func(t *testing.T) {
+		id := a.Diver.Buddies[0].Id
+		if !strings.HasPrefix(id, "buddy-") || strings.Contains(id, "john") {
+			t.Errorf("expected a pseudonymous ID, got %q", id)
+		}
+		links := a.ProfileData.RepetitionGroup[0].Dives[0].InformationBeforeDive.Links
+		if links[1].Ref != id {
+			t.Errorf("expected the dive to link %q, got %+v", id, links)
+		}
+	})
+
+	t.Run("pseudonyms should be stable for the same key", func(t *testing.T) {
+		other := Anonymize(build("John"), AnonymizePolicy{Key: "secret"})
+		if got, want := personalName(other.Diver.Buddies[0].Personal), personalName(a.Diver.Buddies[0].Personal); got != want {
+			t.Errorf("expected the same pseudonym %q, got %q", want, got)
+		}
+		if other := Anonymize(build("Jim"), AnonymizePolicy{Key: "secret"}); other.Diver.Buddies[0].Id == a.Diver.Buddies[0].Id {
+			t.Error("expected different buddies to get different pseudonyms")
+		}
+		if other := Anonymize(build("John"), AnonymizePolicy{Key: "other"}); other.Diver.Buddies[0].Id == a.Diver.Buddies[0].Id {
+			t.Error("expected another key to yield other pseudonyms")
+		}
+	})
+
+	t.Run("dates should be shifted by whole days and coordinates jittered", func(t *testing.T) {
+		shifted := time.Time(a.ProfileData.RepetitionGroup[0].Dives[0].InformationBeforeDive.DateTime)
+		shift := shifted.Sub(start)
+		if shift == 0 || shift%(24*time.Hour) != 0 || shift.Abs() > 365*24*time.Hour {
+			t.Errorf("unexpected shift %v", shift)
+		}
+		if got := time.Time(*a.Generator.DateTime).Sub(time.Time(*u.Generator.DateTime)); got != shift {
+			t.Errorf("expected all dates shifted by %v, got %v", shift, got)
+		}
+
+		geo := a.DiveSite.Sites[0].Geography
+		dLat, dLon := math.Abs(*geo.Latitude-17.3), math.Abs(*geo.Longitude+87.5)
+		if dLat == 0 || dLon == 0 || dLat > 0.01 || dLon > 0.01 {
+			t.Errorf("unexpected jitter %g, %g", dLat, dLon)
+		}
+	})
+
+	t.Run("policy should keep what is asked for", func(t *testing.T) {
+		kept := Anonymize(u, AnonymizePolicy{KeepNames: true, KeepDates: true, KeepCoordinates: true})
+		if personalName(kept.Diver.Owner.Personal) != "Jane Diver" || kept.Diver.Buddies[0].Id != "buddy-john-doe" {
+			t.Error("expected names and IDs to be kept")
+		}
+		if !time.Time(kept.ProfileData.RepetitionGroup[0].Dives[0].InformationBeforeDive.DateTime).Equal(start) {
+			t.Error("expected dates to be kept")
+		}
+		if *kept.DiveSite.Sites[0].Geography.Latitude != 17.3 {
+			t.Error("expected coordinates to be kept")
+		}
+		if kept.Diver.Owner.Personal.BirthDate != nil || kept.Diver.Owner.Contact != nil {
+			t.Error("expected personal data to be removed")
+		}
+	})
+}
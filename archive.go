@@ -0,0 +1,163 @@
+package uddf
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ArchiveDocumentName is the name under which WriteArchive stores the UDDF
+// document inside the zip container.
+const ArchiveDocumentName = "logbook.uddf"
+
+// Archive is a zip container bundling a UDDF document with the media files
+// referenced by the ObjectName of its MediaData entries.
+type Archive struct {
+	UDDF  *UDDF
+	Files fs.FS // archive contents, ObjectName paths resolve relative to its root
+}
+
+// OpenArchive reads the zip container at filename.
+func OpenArchive(filename string) (*Archive, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %s: %w", filename, err)
+	}
+	return ReadArchive(bytes.NewReader(data), int64(len(data)))
+}
+
+// ReadArchive reads a zip container of the given size from r.
+func ReadArchive(r io.ReaderAt, size int64) (*Archive, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open zip archive: %w", err)
+	}
+
+	f, err := findDocument(zr)
+	if err != nil {
+		return nil, err
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s in zip archive: %w", f.Name, err)
+	}
+	defer rc.Close()
+
+	u, err := ParseReader(rc)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Archive{UDDF: u, Files: zr}, nil
+}
+
+// WriteArchive writes u as a zip container to w, together with every file
+// referenced from its MediaData. open is used to read the referenced files
+// and defaults to os.Open when nil. ObjectName paths that are absolute or
+// point outside the current directory are rewritten to media/<name> in the
+// archived document so that they resolve inside the archive; u itself is
+// not modified.
+func WriteArchive(w io.Writer, u *UDDF, open func(objectName string) (io.ReadCloser, error)) error {
+	if u == nil {
+		return fmt.Errorf("UDDF object is nil")
+	}
+	if open == nil {
+		open = func(name string) (io.ReadCloser, error) { return os.Open(name) }
+	}
+
+	doc := *u
+	sources := map[string]string{} // archive name -> original ObjectName
+	if u.MediaData != nil {
+		media := MediaData{
+			AudioFiles: append([]Audio(nil), u.MediaData.AudioFiles...),
+			ImageFiles: append([]Image(nil), u.MediaData.ImageFiles...),
+			VideoFiles: append([]Video(nil), u.MediaData.VideoFiles...),
+		}
+		for i := range media.AudioFiles {
+			media.AudioFiles[i].ObjectName = archiveName(media.AudioFiles[i].ObjectName, sources)
+		}
+		for i := range media.ImageFiles {
+			media.ImageFiles[i].ObjectName = archiveName(media.ImageFiles[i].ObjectName, sources)
+		}
+		for i := range media.VideoFiles {
+			media.VideoFiles[i].ObjectName = archiveName(media.VideoFiles[i].ObjectName, sources)
+		}
+		doc.MediaData = &media
+	}
+
+	data, err := Marshal(&doc)
+	if err != nil {
+		return err
+	}
+
+	zw := zip.NewWriter(w)
+	dw, err := zw.Create(ArchiveDocumentName)
+	if err != nil {
+		return fmt.Errorf("failed to write %s to zip archive: %w", ArchiveDocumentName, err)
+	}
+	if _, err := dw.Write(data); err != nil {
+		return fmt.Errorf("failed to write %s to zip archive: %w", ArchiveDocumentName, err)
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(sources)) {
+		if err := addArchiveFile(zw, name, sources[name], open); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+func addArchiveFile(zw *zip.Writer, name, objectName string, open func(string) (io.ReadCloser, error)) error {
+	rc, err := open(objectName)
+	if err != nil {
+		return fmt.Errorf("failed to open media file %s: %w", objectName, err)
+	}
+	defer rc.Close()
+
+	// Media is typically compressed already, so store it as is
+	fw, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store})
+	if err != nil {
+		return fmt.Errorf("failed to write %s to zip archive: %w", name, err)
+	}
+	if _, err := io.Copy(fw, rc); err != nil {
+		return fmt.Errorf("failed to write %s to zip archive: %w", name, err)
+	}
+	return nil
+}
+
+// archiveName returns the path under which objectName is stored in the
+// archive and records it in sources. Equal object names share one entry,
+// different files that would collide get a numeric suffix.
+func archiveName(objectName string, sources map[string]string) string {
+	name := path.Clean(filepath.ToSlash(objectName))
+	if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") || filepath.VolumeName(objectName) != "" {
+		name = path.Join("media", path.Base(name))
+	}
+	if name == ArchiveDocumentName {
+		name = path.Join("media", name)
+	}
+
+	candidate := name
+	for i := 1; ; i++ {
+		existing, ok := sources[candidate]
+		if !ok {
+			sources[candidate] = objectName
+			return candidate
+		}
+		if existing == objectName {
+			return candidate
+		}
+		ext := path.Ext(name)
+		candidate = fmt.Sprintf("%s-%d%s", strings.TrimSuffix(name, ext), i, ext)
+	}
+}
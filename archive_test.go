@@ -0,0 +1,141 @@
+package uddf
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseCompressed(t *testing.T) {
+	t.Run("should parse gzip compressed file", func(t *testing.T) {
+		uddf, err := ParseFile("testdata/valid.uddf.gz")
+		if err != nil {
+			t.Fatalf("failed to parse compressed UDDF file: %v", err)
+		}
+
+		if uddf.Version != "3.2.3" {
+			t.Errorf("expected version '3.2.3', got '%s'", uddf.Version)
+		}
+	})
+
+	t.Run("should parse zstd compressed file", func(t *testing.T) {
+		uddf, err := ParseFile("testdata/valid.uddf.zst")
+		if err != nil {
+			t.Fatalf("failed to parse compressed UDDF file: %v", err)
+		}
+
+		if uddf.Version != "3.2.3" {
+			t.Errorf("expected version '3.2.3', got '%s'", uddf.Version)
+		}
+	})
+
+	t.Run("corrupt zstd input should fail", func(t *testing.T) {
+		_, err := Parse(append([]byte{0x28, 0xB5, 0x2F, 0xFD}, "payload"...))
+		if err == nil || !strings.Contains(err.Error(), "failed to decompress zstd input") {
+			t.Errorf("expected a zstd error, got %v", err)
+		}
+	})
+
+	t.Run("format without decompressor should fail", func(t *testing.T) {
+		decompressorsMu.RLock()
+		registered := slices.Clone(decompressors)
+		decompressorsMu.RUnlock()
+		t.Cleanup(func() {
+			decompressorsMu.Lock()
+			decompressors = registered
+			decompressorsMu.Unlock()
+		})
+
+		RegisterDecompressor("test", []byte("TST\x00"), nil)
+		_, err := Parse([]byte("TST\x00payload"))
+		if !errors.Is(err, ErrNoDecompressor) {
+			t.Errorf("expected ErrNoDecompressor, got %v", err)
+		}
+	})
+
+	t.Run("should parse zip container", func(t *testing.T) {
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		w, _ := zw.Create("export/dive.UDDF")
+		w.Write([]byte(`<uddf version="3.2.3"></uddf>`))
+		zw.Close()
+
+		uddf, err := Parse(buf.Bytes())
+		if err != nil {
+			t.Fatalf("failed to parse zip container: %v", err)
+		}
+
+		if uddf.Version != "3.2.3" {
+			t.Errorf("expected version '3.2.3', got '%s'", uddf.Version)
+		}
+	})
+}
+
+func TestWriteArchive(t *testing.T) {
+	uddf, err := ParseFile("testdata/valid.uddf")
+	if err != nil {
+		t.Fatalf("failed to parse valid UDDF file: %v", err)
+	}
+
+	uddf.MediaData = &MediaData{
+		ImageFiles: []Image{
+			{ID: "img1", ObjectName: "/home/diver/photos/turtle.jpg"},
+			{ID: "img2", ObjectName: "photos/reef.jpg"},
+		},
+	}
+
+	files := map[string]string{
+		"/home/diver/photos/turtle.jpg": "turtle",
+		"photos/reef.jpg":               "reef",
+	}
+	open := func(name string) (io.ReadCloser, error) {
+		content, ok := files[name]
+		if !ok {
+			return nil, fs.ErrNotExist
+		}
+		return io.NopCloser(strings.NewReader(content)), nil
+	}
+
+	var buf bytes.Buffer
+	if err := WriteArchive(&buf, uddf, open); err != nil {
+		t.Fatalf("failed to write archive: %v", err)
+	}
+
+	if uddf.MediaData.ImageFiles[0].ObjectName != "/home/diver/photos/turtle.jpg" {
+		t.Error("expected WriteArchive not to modify the original document")
+	}
+
+	archive, err := ReadArchive(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("failed to read archive: %v", err)
+	}
+
+	images := archive.UDDF.MediaData.ImageFiles
+	if len(images) != 2 {
+		t.Fatalf("expected 2 images, got %d", len(images))
+	}
+
+	expected := map[string]string{
+		"media/turtle.jpg": "turtle",
+		"photos/reef.jpg":  "reef",
+	}
+	for _, image := range images {
+		content, err := fs.ReadFile(archive.Files, image.ObjectName)
+		if err != nil {
+			t.Errorf("failed to resolve %s inside archive: %v", image.ObjectName, err)
+			continue
+		}
+		if string(content) != expected[image.ObjectName] {
+			t.Errorf("unexpected content for %s: %s", image.ObjectName, content)
+		}
+	}
+
+	if len(archive.UDDF.ProfileData.RepetitionGroup) == 0 {
+		t.Error("expected archived document to keep its profile data")
+	}
+}
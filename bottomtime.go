@@ -0,0 +1,262 @@
+package uddf
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ReservePolicy selects how the gas reserve of a bottom time table is
+// determined.
+type ReservePolicy int
+
+const (
+	// ReserveFixed keeps TankPressureReserve in the tank after surfacing.
+	ReserveFixed ReservePolicy = iota
+	// ReserveThirds uses a third of the fill for descent, bottom time and
+	// ascent, keeping two thirds for the way back and as reserve.
+	ReserveThirds
+	// ReserveRockBottom reserves the gas two divers sharing one tank need to
+	// solve a problem at depth and ascend at stressed consumption. Ascent gas
+	// is part of the reserve.
+	ReserveRockBottom
+)
+
+// BottomTimeOptions tune the bottom time calculation. Zero values select the
+// default given in brackets.
+type BottomTimeOptions struct {
+	Reserve      ReservePolicy
+	DescentRate  float64 // m/s [0.3]
+	AscentRate   float64 // m/s [0.15]
+	ProblemTime  float64 // seconds spent at depth before a rock bottom ascent [60]
+	StressFactor float64 // consumption multiplier for a rock bottom ascent [1.5]
+	Environment  Environment
+	// Mix is the gas in the tank, whose compressibility determines the free
+	// gas of a fill [the mix linked by the table, or air]
+	Mix GasFractions
+}
+
+func (o BottomTimeOptions) withDefaults() BottomTimeOptions {
+	if o.Mix == (GasFractions{}) {
+		o.Mix = Air
+	}
+	setDefault(&o.DescentRate, 0.3)
+	setDefault(&o.AscentRate, 0.15)
+	setDefault(&o.ProblemTime, 60)
+	setDefault(&o.StressFactor, 1.5)
+	return o
+}
+
+// BottomTimes is a bottom time table computed for a BottomTimeTableScope.
+type BottomTimes struct {
+	ID           string
+	Title        string
+	TankPressure float64 // fill pressure in Pa
+	Cells        []BottomTimeCell
+}
+
+// BottomTimeCell is the maximum bottom time for one combination of depth,
+// consumption and tank volume.
+type BottomTimeCell struct {
+	Depth                      float64 // metres
+	BreathingConsumptionVolume float64 // surface consumption in m^3/s
+	TankVolume                 float64 // m^3
+	BottomTime                 float64 // seconds from leaving the surface to leaving the bottom
+	AscentGas                  float64 // free gas needed for the ascent in m^3
+	ReservePressure            float64 // tank pressure kept as reserve in Pa
+}
+
+// CalculateBottomTimeTables computes every table listed in
+// TableGeneration.CalculateBottomTimeTable.
+func (u *UDDF) CalculateBottomTimeTables(opts BottomTimeOptions) ([]*BottomTimes, error) {
+	if u.TableGeneration.CalculateBottomTimeTable == nil {
+		return nil, nil
+	}
+
+	tables := u.TableGeneration.CalculateBottomTimeTable.BottomTimeTables
+	result := make([]*BottomTimes, 0, len(tables))
+	for i := range tables {
+		opts := opts
+		for _, l := range tables[i].Links {
+			if m := u.mix(l.Ref); m != nil && opts.Mix == (GasFractions{}) {
+				opts.Mix = m.Fractions()
+			}
+		}
+		bt, err := tables[i].Calculate(opts)
+		if err != nil {
+			return nil, fmt.Errorf("bottom time table %s: %w", tables[i].ID, err)
+		}
+		result = append(result, bt)
+	}
+	return result, nil
+}
+
+// Calculate computes the maximum bottom time for every cell of the table's
+// scope: each depth and breathing consumption volume in their ranges, for
+// both the begin and end tank volume.
+func (table *BottomTimeTable) Calculate(opts BottomTimeOptions) (*BottomTimes, error) {
+	scope := table.BottomTimeTableScope
+	if scope == nil {
+		return nil, fmt.Errorf("bottom time table has no scope")
+	}
+	if scope.TankPressureBegin == nil || *scope.TankPressureBegin <= 0 {
+		return nil, fmt.Errorf("bottom time table scope needs tankpressurebegin")
+	}
+	if scope.TankVolumeBegin == nil || *scope.TankVolumeBegin <= 0 {
+		return nil, fmt.Errorf("bottom time table scope needs tankvolumebegin")
+	}
+
+	depths, err := scopeRange("divedepth", scope.DiveDepthBegin, scope.DiveDepthEnd, scope.DiveDepthStep)
+	if err != nil {
+		return nil, err
+	}
+	consumptions, err := scopeRange("breathingconsumptionvolume", scope.BreathingConsumptionVolumeBegin, scope.BreathingConsumptionVolumeEnd, scope.BreathingConsumptionVolumeStep)
+	if err != nil {
+		return nil, err
+	}
+	volumes := []float64{*scope.TankVolumeBegin}
+	if scope.TankVolumeEnd != nil && *scope.TankVolumeEnd != *scope.TankVolumeBegin {
+		volumes = append(volumes, *scope.TankVolumeEnd)
+	}
+
+	opts = opts.withDefaults()
+	result := &BottomTimes{ID: table.ID, TankPressure: *scope.TankPressureBegin}
+	if table.Title != nil {
+		result.Title = *table.Title
+	}
+
+	for _, volume := range volumes {
+		for _, depth := range depths {
+			for _, consumption := range consumptions {
+				cell := bottomTimeCell(depth, consumption, volume, *scope.TankPressureBegin, deref(scope.TankPressureReserve), opts)
+				result.Cells = append(result.Cells, cell)
+			}
+		}
+	}
+	return result, nil
+}
+
+func bottomTimeCell(depth, consumption, volume, fill, reserve float64, opts BottomTimeOptions) BottomTimeCell {
+	surface := opts.Environment.Surface() * pascalPerBar
+	pressure := opts.Environment.Pressure(depth) * pascalPerBar
+
+	// Consumption at ambient pressure p in free gas volume per second
+	rate := func(p float64) float64 { return consumption * p / (seaLevelPressure * pascalPerBar) }
+
+	descentTime := depth / opts.DescentRate
+	descentGas := rate((surface+pressure)/2) * descentTime
+	ascentTime := depth / opts.AscentRate
+	ascentGas := rate((surface+pressure)/2) * ascentTime
+
+	cell := BottomTimeCell{
+		Depth:                      depth,
+		BreathingConsumptionVolume: consumption,
+		TankVolume:                 volume,
+		AscentGas:                  ascentGas,
+	}
+
+	switch opts.Reserve {
+	case ReserveThirds:
+		reserve = math.Max(reserve, fill*2/3)
+	case ReserveRockBottom:
+		const divers = 2
+		stressed := divers * opts.StressFactor
+		rockBottom := stressed * (rate(pressure)*opts.ProblemTime + ascentGas)
+		reserve = math.Max(reserve, opts.Mix.FillPressure(volume, rockBottom, 0))
+		ascentGas = 0 // part of the reserve
+	}
+	cell.ReservePressure = reserve
+
+	usable := opts.Mix.FreeGasVolume(volume, fill, 0) - opts.Mix.FreeGasVolume(volume, reserve, 0) - descentGas - ascentGas
+	if usable > 0 {
+		cell.BottomTime = descentTime + usable/rate(pressure)
+	}
+	return cell
+}
+
+// scopeRange expands the begin, end and step values of a scope. A missing
+// end yields the begin value only.
+func scopeRange(name string, begin, end, step *float64) ([]float64, error) {
+	if begin == nil {
+		return nil, fmt.Errorf("scope needs %sbegin", name)
+	}
+	if end == nil || *end == *begin {
+		return []float64{*begin}, nil
+	}
+	if step == nil || *step <= 0 || *end < *begin {
+		return nil, fmt.Errorf("invalid %s range %g-%g", name, *begin, *end)
+	}
+
+	var values []float64
+	for v := *begin; v <= *end+1e-9; v += *step {
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// WriteText renders the table for printing with one block per tank volume,
+// depths as rows and consumptions as columns. Times are given in minutes,
+// consumption in litres per minute and tank volumes in litres.
+func (t *BottomTimes) WriteText(w io.Writer) error {
+	var b strings.Builder
+	if t.Title != "" {
+		fmt.Fprintf(&b, "%s\n", t.Title)
+	}
+	fmt.Fprintf(&b, "Fill: %.0f bar\n", t.TankPressure/pascalPerBar)
+
+	var volume, depth float64 = -1, -1
+	for _, c := range t.Cells {
+		if c.TankVolume != volume {
+			if volume >= 0 {
+				b.WriteString("\n")
+			}
+			volume, depth = c.TankVolume, -1
+			fmt.Fprintf(&b, "\nTank %.1f l\n", volume*1000)
+			b.WriteString("  Depth")
+			for _, h := range t.Cells {
+				if h.TankVolume == volume && h.Depth == c.Depth {
+					fmt.Fprintf(&b, "  %4.0f l/min", h.BreathingConsumptionVolume*60000)
+				}
+			}
+		}
+		if c.Depth != depth {
+			depth = c.Depth
+			fmt.Fprintf(&b, "\n  %4.0fm", depth)
+		}
+		fmt.Fprintf(&b, "  %9.0f'", math.Floor(c.BottomTime/60))
+	}
+	b.WriteString("\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteCSV writes one line per cell. Depths are given in metres, consumption
+// in litres per minute, volumes in litres, pressures in bar and times in
+// minutes.
+func (t *BottomTimes) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"tank_volume", "depth", "consumption", "bottom_time", "ascent_gas", "reserve_pressure"}); err != nil {
+		return err
+	}
+
+	for _, c := range t.Cells {
+		record := []string{
+			strconv.FormatFloat(c.TankVolume*1000, 'f', -1, 64),
+			strconv.FormatFloat(c.Depth, 'f', -1, 64),
+			strconv.FormatFloat(c.BreathingConsumptionVolume*60000, 'f', -1, 64),
+			strconv.FormatFloat(math.Floor(c.BottomTime/60), 'f', 0, 64),
+			strconv.FormatFloat(c.AscentGas*1000, 'f', 0, 64),
+			strconv.FormatFloat(c.ReservePressure/pascalPerBar, 'f', 0, 64),
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
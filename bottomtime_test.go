@@ -0,0 +1,104 @@
+package uddf
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+)
+
+func TestBottomTimeTable(t *testing.T) {
+	table := &BottomTimeTable{
+		ID: "bt1",
+		BottomTimeTableScope: &BottomTimeTableScope{
+			BreathingConsumptionVolumeBegin: ptr(0.00025), // 15 l/min
+			BreathingConsumptionVolumeEnd:   ptr(0.00035),
+			BreathingConsumptionVolumeStep:  ptr(0.00005),
+			DiveDepthBegin:                  ptr(10.0),
+			DiveDepthEnd:                    ptr(40.0),
+			DiveDepthStep:                   ptr(10.0),
+			TankPressureBegin:               ptr(200e5),
+			TankPressureReserve:             ptr(50e5),
+			TankVolumeBegin:                 ptr(0.012),
+			TankVolumeEnd:                   ptr(0.015),
+		},
+	}
+
+	fixed, err := table.Calculate(BottomTimeOptions{})
+	if err != nil {
+		t.Fatalf("failed to calculate bottom time table: %v", err)
+	}
+	if len(fixed.Cells) != 2*4*3 {
+		t.Fatalf("expected 24 cells, got %d", len(fixed.Cells))
+	}
+
+	t.Run("fixed reserve should match hand calculation", func(t *testing.T) {
+		// 12 l at 10 m, 15 l/min: 150 bar usable = 1689 l free gas of real
+		// air, 1776.5 l of an ideal gas
+		cell := fixed.Cells[0]
+		if cell.Depth != 10 || cell.TankVolume != 0.012 {
+			t.Fatalf("unexpected first cell %+v", cell)
+		}
+		if cell.BottomTime < 55*60 || cell.BottomTime > 57*60 {
+			t.Errorf("expected bottom time of about 56 min, got %.1f min", cell.BottomTime/60)
+		}
+		if cell.ReservePressure != 50e5 {
+			t.Errorf("expected reserve of 50 bar, got %v", cell.ReservePressure)
+		}
+	})
+
+	t.Run("bottom time should shrink with depth and consumption", func(t *testing.T) {
+		for i := 1; i < len(fixed.Cells); i++ {
+			prev, cell := fixed.Cells[i-1], fixed.Cells[i]
+			if prev.TankVolume == cell.TankVolume && prev.Depth == cell.Depth && cell.BottomTime >= prev.BottomTime {
+				t.Errorf("expected shorter bottom time for higher consumption, got %+v after %+v", cell, prev)
+			}
+		}
+	})
+
+	t.Run("rule of thirds should be more conservative", func(t *testing.T) {
+		thirds, err := table.Calculate(BottomTimeOptions{Reserve: ReserveThirds})
+		if err != nil {
+			t.Fatalf("failed to calculate bottom time table: %v", err)
+		}
+		for i := range thirds.Cells {
+			if thirds.Cells[i].BottomTime >= fixed.Cells[i].BottomTime {
+				t.Errorf("expected thirds to reduce bottom time, got %+v", thirds.Cells[i])
+			}
+		}
+	})
+
+	t.Run("rock bottom reserve should grow with depth", func(t *testing.T) {
+		rockBottom, err := table.Calculate(BottomTimeOptions{Reserve: ReserveRockBottom})
+		if err != nil {
+			t.Fatalf("failed to calculate bottom time table: %v", err)
+		}
+		shallow, deep := rockBottom.Cells[2], rockBottom.Cells[11]
+		if deep.ReservePressure <= shallow.ReservePressure {
+			t.Errorf("expected larger reserve at %.0f m than at %.0f m", deep.Depth, shallow.Depth)
+		}
+		if deep.ReservePressure <= 50e5 {
+			t.Errorf("expected rock bottom above the fixed reserve at 40 m, got %v", deep.ReservePressure)
+		}
+	})
+
+	t.Run("should render CSV", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := fixed.WriteCSV(&buf); err != nil {
+			t.Fatalf("failed to write CSV: %v", err)
+		}
+		records, err := csv.NewReader(&buf).ReadAll()
+		if err != nil {
+			t.Fatalf("failed to read CSV: %v", err)
+		}
+		if len(records) != 25 {
+			t.Errorf("expected header and 24 records, got %d", len(records))
+		}
+	})
+
+	t.Run("missing scope should fail", func(t *testing.T) {
+		_, err := (&BottomTimeTable{ID: "empty"}).Calculate(BottomTimeOptions{})
+		if err == nil {
+			t.Error("expected error for missing scope, got nil")
+		}
+	})
+}
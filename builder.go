@@ -0,0 +1,335 @@
+package uddf
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Version is the UDDF version written by the Builder.
+const Version = "3.2.3"
+
+// Builder constructs UDDF documents. It generates unique IDs and wires Link
+// references between dives and the mixes, sites and buddies they use:
+//
+//	u, err := uddf.New().
+//		Owner("Jane", "Diver").
+//		AddMix("Nitrox 32", 0.32, 0).
+//		AddSite("Blue Hole", 27.52, 34.54).
+//		AddDive(func(d *uddf.DiveBuilder) {
+//			d.At(start).Site("Blue Hole").Tank("Nitrox 32", 0.012, 200e5, 60e5)
+//			d.Sample(0, 0).Sample(20*time.Minute, 18.5).Sample(45*time.Minute, 0)
+//		}).
+//		Build()
+//
+// Errors such as references to unknown mixes are collected and returned by
+// Build.
+type Builder struct {
+	doc      *UDDF
+	ids      map[string]bool
+	owner    bool
+	mixes    map[string]string // ID or name -> ID
+	sites    map[string]string
+	buddies  map[string]string
+	dives    int
+	tanks    int
+	errs     []error
+	maxPo2   float64
+	complete bool
+}
+
+// New returns a Builder for an empty document.
+func New() *Builder {
+	return &Builder{
+		doc: &UDDF{
+			Version:     Version,
+			ProfileData: ProfileData{RepetitionGroup: []RepetitionGroup{{ID: ptr("rg-1")}}},
+		},
+		ids:     map[string]bool{"rg-1": true},
+		mixes:   map[string]string{},
+		sites:   map[string]string{},
+		buddies: map[string]string{},
+	}
+}
+
+// Generator records the program that created the document.
+func (b *Builder) Generator(name, version string) *Builder {
+	b.doc.Generator = &Generator{
+		Name:     name,
+		Type:     ptr("logbook"),
+		Version:  ptr(version),
+		DateTime: ptr(Time(time.Now().UTC())),
+	}
+	return b
+}
+
+// Owner sets the owner of the document.
+func (b *Builder) Owner(firstName, lastName string) *Builder {
+	owner := &b.doc.Diver.Owner
+	if owner.Id == "" {
+		owner.Id = b.id("owner", "")
+	}
+	owner.Personal.FirstName = ptr(firstName)
+	owner.Personal.LastName = ptr(lastName)
+	b.owner = true
+	return b
+}
+
+// AddBuddy adds a buddy, who can be referenced from dives by ID or by
+// "first last" name.
+func (b *Builder) AddBuddy(firstName, lastName string) *Builder {
+	name := strings.TrimSpace(firstName + " " + lastName)
+	buddy := Buddy{}
+	buddy.Id = b.id("buddy", name)
+	buddy.Personal.FirstName = ptr(firstName)
+	buddy.Personal.LastName = ptr(lastName)
+
+	b.doc.Diver.Buddies = append(b.doc.Diver.Buddies, buddy)
+	b.buddies[buddy.Id] = buddy.Id
+	b.buddies[name] = buddy.Id
+	return b
+}
+
+// AddMix adds a breathing gas with the given oxygen and helium fractions. It
+// can be referenced from dives by ID or name.
+func (b *Builder) AddMix(name string, o2, he float64) *Builder {
+	if o2 < 0 || he < 0 || o2+he > 1 {
+		b.errs = append(b.errs, fmt.Errorf("mix %q: invalid fractions o2=%g he=%g", name, o2, he))
+		return b
+	}
+
+	mix := GasFractions{O2: o2, He: he, N2: 1 - o2 - he}.Mix(b.id("mix", name))
+	mix.Name = name
+	if b.doc.GasDefinitions == nil {
+		b.doc.GasDefinitions = &GasDefinitions{}
+	}
+	b.doc.GasDefinitions.Mixes = append(b.doc.GasDefinitions.Mixes, mix)
+	b.mixes[mix.ID] = mix.ID
+	b.mixes[name] = mix.ID
+	return b
+}
+
+// CompleteMixes fills in the derived fields of all mixes when the document
+// is built, limiting the oxygen partial pressure to maxPo2 bar, see
+// UDDF.CompleteMixes.
+func (b *Builder) CompleteMixes(maxPo2 float64) *Builder {
+	b.complete = true
+	b.maxPo2 = maxPo2
+	return b
+}
+
+// AddSite adds a dive site at the given coordinates. It can be referenced
+// from dives by ID or name.
+func (b *Builder) AddSite(name string, latitude, longitude float64) *Builder {
+	site := Site{
+		ID:        b.id("site", name),
+		Name:      name,
+		Geography: &Geography{Location: name, Latitude: ptr(latitude), Longitude: ptr(longitude)},
+	}
+	if b.doc.DiveSite == nil {
+		b.doc.DiveSite = &DiveSite{}
+	}
+	b.doc.DiveSite.Sites = append(b.doc.DiveSite.Sites, site)
+	b.sites[site.ID] = site.ID
+	b.sites[name] = site.ID
+	return b
+}
+
+// AddDive adds a dive to the current repetition group and passes it to fn
+// for configuration.
+func (b *Builder) AddDive(fn func(d *DiveBuilder)) *Builder {
+	b.dives++
+	d := &DiveBuilder{b: b, dive: Dive{ID: b.id("dive", fmt.Sprint(b.dives))}}
+	if fn != nil {
+		fn(d)
+	}
+	d.finish()
+
+	groups := b.doc.ProfileData.RepetitionGroup
+	groups[len(groups)-1].Dives = append(groups[len(groups)-1].Dives, d.dive)
+	return b
+}
+
+// NewRepetitionGroup starts a new repetition group for the following dives.
+func (b *Builder) NewRepetitionGroup() *Builder {
+	groups := &b.doc.ProfileData.RepetitionGroup
+	if len((*groups)[len(*groups)-1].Dives) == 0 {
+		return b
+	}
+	*groups = append(*groups, RepetitionGroup{ID: ptr(b.id("rg", fmt.Sprint(len(*groups)+1)))})
+	return b
+}
+
+// Build returns the document, or the errors collected while building it
+// joined with any validation error.
+func (b *Builder) Build() (*UDDF, error) {
+	errs := b.errs
+	if !b.owner {
+		errs = append(errs, errors.New("owner is required"))
+	}
+	if b.complete {
+		b.doc.CompleteMixes(b.maxPo2)
+	}
+	if len(errs) == 0 {
+		if err := b.doc.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return b.doc, nil
+}
+
+// id returns a new document-wide unique ID made of prefix and name.
+func (b *Builder) id(prefix, name string) string {
+	base := prefix
+	if slug := slugify(name); slug != "" {
+		base += "-" + slug
+	}
+
+	id := base
+	for i := 2; b.ids[id]; i++ {
+		id = fmt.Sprintf("%s-%d", base, i)
+	}
+	b.ids[id] = true
+	return id
+}
+
+func slugify(s string) string {
+	var sb strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			sb.WriteRune(r)
+			dash = false
+		} else if !dash && sb.Len() > 0 {
+			sb.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "-")
+}
+
+// DiveBuilder configures a single dive, see Builder.AddDive.
+type DiveBuilder struct {
+	b        *Builder
+	dive     Dive
+	duration bool
+	depth    bool
+}
+
+// Dive returns the dive under construction for changes not covered by the
+// builder methods.
+func (d *DiveBuilder) Dive() *Dive {
+	return &d.dive
+}
+
+// At sets the start time of the dive.
+func (d *DiveBuilder) At(t time.Time) *DiveBuilder {
+	d.dive.InformationBeforeDive.DateTime = Time(t)
+	return d
+}
+
+// Number sets the dive number.
+func (d *DiveBuilder) Number(n int) *DiveBuilder {
+	d.dive.InformationBeforeDive.DiveNumber = ptr(n)
+	return d
+}
+
+// Duration sets the dive duration. It defaults to the time of the last
+// sample.
+func (d *DiveBuilder) Duration(duration time.Duration) *DiveBuilder {
+	d.dive.InformationAfterDive.DiveDuration = duration.Seconds()
+	d.duration = true
+	return d
+}
+
+// GreatestDepth sets the greatest depth in metres. It defaults to the
+// deepest sample.
+func (d *DiveBuilder) GreatestDepth(depth float64) *DiveBuilder {
+	d.dive.InformationAfterDive.GreatestDepth = depth
+	d.depth = true
+	return d
+}
+
+// Site links the dive to a site given by ID or name.
+func (d *DiveBuilder) Site(site string) *DiveBuilder {
+	return d.link(d.b.sites, "site", site)
+}
+
+// Buddy links the dive to a buddy given by ID or name.
+func (d *DiveBuilder) Buddy(buddy string) *DiveBuilder {
+	return d.link(d.b.buddies, "buddy", buddy)
+}
+
+func (d *DiveBuilder) link(refs map[string]string, kind, key string) *DiveBuilder {
+	id, ok := refs[key]
+	if !ok {
+		d.b.errs = append(d.b.errs, fmt.Errorf("dive %s: unknown %s %q", d.dive.ID, kind, key))
+		return d
+	}
+	d.dive.InformationBeforeDive.Links = append(d.dive.InformationBeforeDive.Links, Link{Ref: id})
+	return d
+}
+
+// Tank adds a tank filled with the mix given by ID or name. The volume is
+// given in m^3, pressures in Pa.
+func (d *DiveBuilder) Tank(mix string, volume, pressureBegin, pressureEnd float64) *DiveBuilder {
+	id, ok := d.b.mixes[mix]
+	if !ok {
+		d.b.errs = append(d.b.errs, fmt.Errorf("dive %s: unknown mix %q", d.dive.ID, mix))
+		return d
+	}
+
+	d.b.tanks++
+	d.dive.TankData = append(d.dive.TankData, TankData{
+		ID:                d.b.id("tank", fmt.Sprint(d.b.tanks)),
+		Links:             []Link{{Ref: id}},
+		TankPressureBegin: pressureBegin,
+		TankPressureEnd:   pressureEnd,
+		TankVolume:        ptr(volume),
+	})
+	return d
+}
+
+// Sample adds a waypoint at the given time into the dive. The first sample
+// switches to the mix of the first tank.
+func (d *DiveBuilder) Sample(at time.Duration, depth float64) *DiveBuilder {
+	if d.dive.Samples == nil {
+		d.dive.Samples = &Samples{}
+	}
+	d.dive.Samples.Waypoints = append(d.dive.Samples.Waypoints, Waypoint{DiveTime: at.Seconds(), Depth: depth})
+	return d
+}
+
+// Notes adds paragraphs to the notes of the dive.
+func (d *DiveBuilder) Notes(paras ...string) *DiveBuilder {
+	if d.dive.InformationAfterDive.Notes == nil {
+		d.dive.InformationAfterDive.Notes = &Notes{}
+	}
+	d.dive.InformationAfterDive.Notes.Paras = append(d.dive.InformationAfterDive.Notes.Paras, paras...)
+	return d
+}
+
+// finish derives the fields that were not set explicitly from the samples.
+func (d *DiveBuilder) finish() {
+	if d.dive.Samples == nil || len(d.dive.Samples.Waypoints) == 0 {
+		return
+	}
+	waypoints := d.dive.Samples.Waypoints
+
+	if len(d.dive.TankData) > 0 && waypoints[0].SwitchMix == nil {
+		waypoints[0].SwitchMix = &SwitchMix{Ref: d.dive.TankData[0].Links[0].Ref}
+	}
+	if !d.duration {
+		d.dive.InformationAfterDive.DiveDuration = waypoints[len(waypoints)-1].DiveTime
+	}
+	if !d.depth {
+		for _, w := range waypoints {
+			d.dive.InformationAfterDive.GreatestDepth = math.Max(d.dive.InformationAfterDive.GreatestDepth, w.Depth)
+		}
+	}
+}
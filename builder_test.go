@@ -0,0 +1,120 @@
+package uddf
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuilder(t *testing.T) {
+	start := time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC)
+
+	t.Run("document should be valid and wired", func(t *testing.T) {
+		u, err := New().
+			Generator("test", "1.0").
+			Owner("Jane", "Diver").
+			AddBuddy("John", "Buddy").
+			AddMix("Nitrox 32", 0.32, 0).
+			AddMix("Nitrox 32", 0.32, 0).
+			AddSite("Blue Hole", 27.52, 34.54).
+			AddDive(func(d *DiveBuilder) {
+				d.At(start).Number(1).Site("Blue Hole").Buddy("John Buddy").Tank("mix-nitrox-32-2", 0.012, 200e5, 60e5)
+				d.Sample(0, 0).Sample(20*time.Minute, 18.5).Sample(45*time.Minute, 0)
+			}).
+			NewRepetitionGroup().
+			AddDive(func(d *DiveBuilder) {
+				d.At(start.Add(3 * time.Hour)).Site("site-blue-hole").Duration(40 * time.Minute)
+			}).
+			Build()
+		if err != nil {
+			t.Fatalf("failed to build document: %v", err)
+		}
+
+		if mixes := u.GasDefinitions.Mixes; mixes[0].ID != "mix-nitrox-32" || mixes[1].ID != "mix-nitrox-32-2" {
+			t.Errorf("expected unique mix IDs, got %s and %s", mixes[0].ID, mixes[1].ID)
+		}
+		if len(u.ProfileData.RepetitionGroup) != 2 {
+			t.Fatalf("expected 2 repetition groups, got %d", len(u.ProfileData.RepetitionGroup))
+		}
+
+		dive := u.ProfileData.RepetitionGroup[0].Dives[0]
+		if dive.ID != "dive-1" {
+			t.Errorf("expected dive ID 'dive-1', got '%s'", dive.ID)
+		}
+		refs := []string{}
+		for _, l := range dive.InformationBeforeDive.Links {
+			refs = append(refs, l.Ref)
+		}
+		if got := strings.Join(refs, ","); got != "site-blue-hole,buddy-john-buddy" {
+			t.Errorf("expected links to site and buddy, got %s", got)
+		}
+		if dive.TankData[0].Links[0].Ref != "mix-nitrox-32-2" {
+			t.Errorf("expected tank to link mix-nitrox-32-2, got %s", dive.TankData[0].Links[0].Ref)
+		}
+		if w := dive.Samples.Waypoints[0]; w.SwitchMix == nil || w.SwitchMix.Ref != "mix-nitrox-32-2" {
+			t.Errorf("expected first waypoint to switch to the tank mix, got %+v", w.SwitchMix)
+		}
+		if dive.InformationAfterDive.GreatestDepth != 18.5 || dive.InformationAfterDive.DiveDuration != 2700 {
+			t.Errorf("expected depth and duration from samples, got %v m and %v s",
+				dive.InformationAfterDive.GreatestDepth, dive.InformationAfterDive.DiveDuration)
+		}
+
+		data, err := Marshal(u)
+		if err != nil {
+			t.Fatalf("failed to marshal document: %v", err)
+		}
+		if _, err := Parse(data); err != nil {
+			t.Errorf("failed to parse built document: %v", err)
+		}
+	})
+
+	t.Run("completed mixes should be written in SI units", func(t *testing.T) {
+		u := buildDocument(t, func(b *Builder) { b.AddMix("Nitrox 32", 0.32, 0).CompleteMixes(1.4) })
+		data, err := Marshal(u)
+		if err != nil {
+			t.Fatalf("failed to marshal document: %v", err)
+		}
+		if !strings.Contains(string(data), "<maximumpo2>140000</maximumpo2>") {
+			t.Errorf("expected the maximum ppO2 in Pa, got:\n%s", data)
+		}
+		if mod := *u.GasDefinitions.Mixes[0].MaximumOperationDepth; mod < 33 || mod > 34 {
+			t.Errorf("expected a MOD of 33.3 m, got %v", mod)
+		}
+	})
+
+	t.Run("unknown references should fail the build", func(t *testing.T) {
+		_, err := New().
+			Owner("Jane", "Diver").
+			AddDive(func(d *DiveBuilder) {
+				d.Site("Nowhere").Tank("Air", 0.012, 200e5, 50e5)
+			}).
+			Build()
+		if err == nil {
+			t.Fatal("expected error for unknown references")
+		}
+		for _, want := range []string{`unknown site "Nowhere"`, `unknown mix "Air"`} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("expected error to contain %q, got %v", want, err)
+			}
+		}
+	})
+
+	t.Run("missing owner should fail the build", func(t *testing.T) {
+		if _, err := New().Build(); err == nil || !strings.Contains(err.Error(), "owner is required") {
+			t.Errorf("expected missing owner error, got %v", err)
+		}
+	})
+}
+
+// buildDocument returns a document owned by Jane Diver and set up by fn,
+// failing the test if it does not build.
+func buildDocument(t *testing.T, fn func(b *Builder)) *UDDF {
+	t.Helper()
+	b := New().Owner("Jane", "Diver")
+	fn(b)
+	u, err := b.Build()
+	if err != nil {
+		t.Fatalf("failed to build document: %v", err)
+	}
+	return u
+}
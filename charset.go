@@ -0,0 +1,74 @@
+package uddf
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/unicode"
+)
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+)
+
+// newDecoder returns an XML decoder for data that transparently handles byte
+// order marks, UTF-16 input and legacy encodings declared in the XML
+// declaration (e.g. ISO-8859-1 or Windows-1252).
+func newDecoder(data []byte) *xml.Decoder {
+	enc, data := sniffEncoding(data)
+	if enc == nil {
+		decoder := xml.NewDecoder(bytes.NewReader(data))
+		decoder.CharsetReader = charsetReader
+		return decoder
+	}
+
+	// The encoding is known from the byte order mark, which takes
+	// precedence over whatever the XML declaration claims.
+	decoder := xml.NewDecoder(enc.NewDecoder().Reader(bytes.NewReader(data)))
+	decoder.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	return decoder
+}
+
+// sniffEncoding detects UTF-8 and UTF-16 input by its byte order mark or, for
+// UTF-16 without a BOM, by the encoding of the leading '<'. It returns the
+// detected encoding (nil if the input should be read as declared) and data
+// with any byte order mark removed.
+func sniffEncoding(data []byte) (encoding.Encoding, []byte) {
+	switch {
+	case bytes.HasPrefix(data, bomUTF8):
+		return unicode.UTF8, data[len(bomUTF8):]
+	case bytes.HasPrefix(data, bomUTF16BE):
+		return unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM), data[len(bomUTF16BE):]
+	case bytes.HasPrefix(data, bomUTF16LE):
+		return unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM), data[len(bomUTF16LE):]
+	case len(data) >= 2 && data[0] == 0 && data[1] == '<':
+		return unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM), data
+	case len(data) >= 2 && data[0] == '<' && data[1] == 0:
+		return unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM), data
+	}
+	return nil, data
+}
+
+// charsetReader converts input in the encoding named by label to UTF-8. It
+// is used by the XML decoder for any encoding declared other than UTF-8.
+func charsetReader(label string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(strings.TrimSpace(label)) {
+	case "utf-8", "utf8", "us-ascii", "ascii":
+		return input, nil
+	}
+
+	enc, err := htmlindex.Get(label)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported character encoding %q: %w", label, err)
+	}
+	return enc.NewDecoder().Reader(input), nil
+}
@@ -0,0 +1,200 @@
+package uddf
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// Issue severities
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Issue kinds
+const (
+	IssueStruct    = "struct"    // violates a validation rule of the model
+	IssueReference = "reference" // duplicate ID or dangling reference
+	IssueSemantic  = "semantic"  // implausible data, e.g. samples going back in time
+)
+
+// Issue is a problem found by Check.
+type Issue struct {
+	Severity string `json:"severity"`
+	Kind     string `json:"kind"`
+	Path     string `json:"path"` // XML path, or the Go field path for struct issues
+	Message  string `json:"message"`
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%s: %s: %s", i.Severity, i.Path, i.Message)
+}
+
+// Check validates the document like Validate, and additionally checks that
+// IDs are unique, references resolve and dives and mixes are plausible.
+func (u *UDDF) Check() []Issue {
+	if u == nil {
+		return []Issue{{Severity: SeverityError, Kind: IssueStruct, Message: "UDDF object is nil"}}
+	}
+
+	issues := structIssues(u.Validate())
+	issues = append(issues, u.referenceIssues()...)
+	issues = append(issues, u.semanticIssues()...)
+	return issues
+}
+
+// HasErrors reports whether any of the issues is an error.
+func HasErrors(issues []Issue) bool {
+	for _, i := range issues {
+		if i.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+func structIssues(err error) []Issue {
+	if err == nil {
+		return nil
+	}
+
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return []Issue{{Severity: SeverityError, Kind: IssueStruct, Message: err.Error()}}
+	}
+
+	issues := make([]Issue, len(verrs))
+	for i, fe := range verrs {
+		issues[i] = Issue{
+			Severity: SeverityError,
+			Kind:     IssueStruct,
+			Path:     fe.Namespace(),
+			Message:  fmt.Sprintf("failed on the '%s' rule", fe.Tag()),
+		}
+	}
+	return issues
+}
+
+// referenceAttrs are the attributes holding the ID of another element.
+var referenceAttrs = map[string]bool{"@ref": true, "@deviceref": true, "@tankref": true}
+
+func (u *UDDF) referenceIssues() []Issue {
+	var issues []Issue
+	ids := map[string]string{}
+	type reference struct{ path, id string }
+	var refs []reference
+
+	Walk(u, Visitor{Node: func(n *Node) WalkAction {
+		s, ok := n.Value.(*string)
+		if !ok || *s == "" {
+			return Continue
+		}
+		switch {
+		case n.Name == "@id":
+			if first, dup := ids[*s]; dup {
+				issues = append(issues, Issue{
+					Severity: SeverityError,
+					Kind:     IssueReference,
+					Path:     n.Path,
+					Message:  fmt.Sprintf("duplicate id %q, first used at %s", *s, first),
+				})
+			} else {
+				ids[*s] = n.Path
+			}
+		case referenceAttrs[n.Name]:
+			refs = append(refs, reference{path: n.Path, id: *s})
+		}
+		return Continue
+	}})
+
+	for _, r := range refs {
+		if _, ok := ids[r.id]; !ok {
+			issues = append(issues, Issue{
+				Severity: SeverityError,
+				Kind:     IssueReference,
+				Path:     r.path,
+				Message:  fmt.Sprintf("unknown id %q", r.id),
+			})
+		}
+	}
+	return issues
+}
+
+// maximumPo2 is the oxygen partial pressure in bar above which breathing a
+// mix is reported.
+const maximumPo2 = 1.6
+
+func (u *UDDF) semanticIssues() []Issue {
+	var issues []Issue
+	add := func(severity, path, format string, args ...any) {
+		issues = append(issues, Issue{Severity: severity, Kind: IssueSemantic, Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	Walk(u, Visitor{
+		OnMix: func(n *Node, m *Mix) WalkAction {
+			others := deref(m.O2) + deref(m.He) + deref(m.Ar) + deref(m.H2)
+			switch {
+			case others > 1+1e-6:
+				add(SeverityError, n.Path, "gas fractions add up to %.3f", others)
+			case m.N2 != nil && math.Abs(others+*m.N2-1) > 0.01:
+				add(SeverityWarning, n.Path, "gas fractions add up to %.3f", others+*m.N2)
+			}
+			return SkipChildren
+		},
+		OnDive: func(n *Node, d *Dive) WalkAction {
+			u.diveIssues(n.Path, d, add)
+			return SkipChildren
+		},
+	})
+	u.seriesIssues(add)
+	return issues
+}
+
+func (u *UDDF) diveIssues(path string, d *Dive, add func(severity, path, format string, args ...any)) {
+	for i, t := range d.TankData {
+		if t.TankPressureBegin > 0 && t.TankPressureEnd > t.TankPressureBegin {
+			add(SeverityWarning, fmt.Sprintf("%s/tankdata[%d]", path, i+1),
+				"tank pressure rises from %.0f to %.0f bar", t.TankPressureBegin/pascalPerBar, t.TankPressureEnd/pascalPerBar)
+		}
+	}
+
+	if d.Samples == nil || len(d.Samples.Waypoints) == 0 {
+		return
+	}
+
+	env := u.Environment(d)
+	var deepest, last float64
+	var mix *Mix
+	exceeded := map[*Mix]bool{}
+	for i, w := range d.Samples.Waypoints {
+		wpath := fmt.Sprintf("%s/samples/waypoint[%d]", path, i+1)
+		if i > 0 && w.DiveTime < last {
+			add(SeverityError, wpath, "dive time %.0f s is before the previous sample at %.0f s", w.DiveTime, last)
+		}
+		if w.Depth < 0 {
+			add(SeverityError, wpath, "negative depth %.1f m", w.Depth)
+		}
+		if w.SwitchMix != nil {
+			mix = u.mix(w.SwitchMix.Ref)
+		}
+		if mix != nil && !exceeded[mix] && w.Depth > env.MOD(mix, maximumPo2)+0.5 {
+			exceeded[mix] = true
+			add(SeverityWarning, wpath, "%s breathed at %.1f m exceeds a ppO2 of %.1f bar", mix.Name, w.Depth, maximumPo2)
+		}
+		deepest = math.Max(deepest, w.Depth)
+		last = math.Max(last, w.DiveTime)
+	}
+
+	info := d.InformationAfterDive
+	if info.GreatestDepth+0.1 < deepest {
+		add(SeverityWarning, path+"/informationafterdive/greatestdepth",
+			"greatest depth %.1f m is less than the deepest sample at %.1f m", info.GreatestDepth, deepest)
+	}
+	if info.DiveDuration > 0 && info.DiveDuration+1 < last {
+		add(SeverityWarning, path+"/informationafterdive/diveduration",
+			"dive duration %.0f s is shorter than the samples (%.0f s)", info.DiveDuration, last)
+	}
+}
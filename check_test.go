@@ -0,0 +1,57 @@
+package uddf
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	t.Run("valid document should have no issues", func(t *testing.T) {
+		u, err := ParseFile("testdata/valid.uddf")
+		if err != nil {
+			t.Fatalf("failed to parse file: %v", err)
+		}
+		if issues := u.Check(); len(issues) != 0 {
+			t.Errorf("expected no issues, got %v", issues)
+		}
+	})
+
+	t.Run("should report struct, reference and semantic issues", func(t *testing.T) {
+		u := buildDocument(t, func(b *Builder) {
+			b.AddMix("Nitrox 50", 0.5, 0).
+				AddDive(func(d *DiveBuilder) {
+					d.Tank("Nitrox 50", 0.012, 50e5, 200e5).GreatestDepth(20)
+					d.Sample(0, 0).Sample(600, 30).Sample(300, 5)
+				})
+		})
+
+		u.GasDefinitions.Mixes = append(u.GasDefinitions.Mixes, Mix{ID: "mix-nitrox-50", O2: ptr(0.8), He: ptr(0.3)})
+		u.ProfileData.RepetitionGroup[0].Dives[0].InformationBeforeDive.Links = []Link{{Ref: "nowhere"}}
+
+		var got []string
+		for _, issue := range u.Check() {
+			got = append(got, issue.Severity+" "+issue.Kind+" "+issue.Message)
+		}
+		expected := []string{
+			"error struct failed on the 'required' rule",
+			`error reference duplicate id "mix-nitrox-50"`,
+			`error reference unknown id "nowhere"`,
+			"error semantic gas fractions add up to 1.100",
+			"warning semantic tank pressure rises from 50 to 200 bar",
+			"warning semantic Nitrox 50 breathed at 30.0 m exceeds a ppO2 of 1.6 bar",
+			"error semantic dive time 0 s is before the previous sample",
+			"warning semantic greatest depth 20.0 m is less than the deepest sample at 30.0 m",
+		}
+		if len(got) != len(expected) {
+			t.Fatalf("expected %d issues, got %d:\n%s", len(expected), len(got), strings.Join(got, "\n"))
+		}
+		for i, want := range expected {
+			if !strings.HasPrefix(got[i], want) {
+				t.Errorf("issue %d: expected %q, got %q", i, want, got[i])
+			}
+		}
+		if !HasErrors(u.Check()) {
+			t.Error("expected HasErrors to be true")
+		}
+	})
+}
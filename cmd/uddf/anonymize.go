@@ -0,0 +1,35 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/Flipez/go-uddf"
+)
+
+func runAnonymize(args []string, e *env) int {
+	fs := flag.NewFlagSet("anonymize", flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	var policy uddf.AnonymizePolicy
+	fs.StringVar(&policy.Key, "key", "", "secret for stable pseudonyms, date shifts and jitter across files (default: random)")
+	fs.BoolVar(&policy.KeepNames, "keep-names", false, "keep the names of people")
+	fs.BoolVar(&policy.KeepDates, "keep-dates", false, "keep dates and times")
+	fs.BoolVar(&policy.KeepCoordinates, "keep-coordinates", false, "keep coordinates")
+	out := fs.String("o", "", "output file (default: stdout)")
+	fs.Usage = func() {
+		fmt.Fprintln(e.stderr, "Usage: uddf anonymize [flags] [file]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+
+	u, err := load(fs.Arg(0), e)
+	if err != nil {
+		return fail(e, err)
+	}
+	if err := writeDocument(*out, uddf.Anonymize(u, policy), e); err != nil {
+		return fail(e, err)
+	}
+	return exitOK
+}
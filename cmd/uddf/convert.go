@@ -0,0 +1,323 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Flipez/go-uddf"
+)
+
+var formats = []string{"uddf", "json", "csv", "gpx"}
+
+// runConvert converts between formats:
+//
+//	uddf  UDDF XML, optionally compressed
+//	json  the Go model as JSON
+//	csv   one line per depth sample: dive,datetime,divetime,depth,temperature
+//	      in seconds, metres and Kelvin
+//	gpx   the dive sites as GPX waypoints
+func runConvert(args []string, e *env) int {
+	fs := flag.NewFlagSet("convert", flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	from := fs.String("from", "", "input format (default: from the file extension, else uddf)")
+	to := fs.String("to", "", "output format (default: from the -o extension, else uddf)")
+	out := fs.String("o", "", "output file (default: stdout)")
+	fs.Usage = func() {
+		fmt.Fprintf(e.stderr, "Usage: uddf convert [flags] [file]\n\nFormats: %s\n", strings.Join(formats, ", "))
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+
+	in := fs.Arg(0)
+	if *from == "" {
+		*from = formatOf(in)
+	}
+	if *to == "" {
+		*to = formatOf(*out)
+	}
+
+	u, err := read(in, *from, e)
+	if err != nil {
+		return fail(e, err)
+	}
+	if *to == "uddf" {
+		err = writeDocument(*out, u, e)
+	} else {
+		err = write(*out, *to, u, e)
+	}
+	if err != nil {
+		return fail(e, err)
+	}
+	return exitOK
+}
+
+// formatOf returns the format given by the extension of path, defaulting to
+// uddf.
+func formatOf(path string) string {
+	ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	for _, f := range formats {
+		if ext == f {
+			return f
+		}
+	}
+	return "uddf"
+}
+
+func read(path, format string, e *env) (*uddf.UDDF, error) {
+	if format == "uddf" {
+		return load(path, e)
+	}
+
+	var r io.Reader = e.stdin
+	if path != "" && path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	switch format {
+	case "json":
+		var u uddf.UDDF
+		if err := json.NewDecoder(r).Decode(&u); err != nil {
+			return nil, fmt.Errorf("failed to decode JSON: %w", err)
+		}
+		return &u, nil
+	case "csv":
+		return readCSV(r)
+	case "gpx":
+		return readGPX(r)
+	}
+	return nil, fmt.Errorf("unknown format %q", format)
+}
+
+func write(path, format string, u *uddf.UDDF, e *env) error {
+	w, closeFn, err := create(path, e)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case "json":
+		err = writeJSON(w, u)
+	case "csv":
+		err = writeCSV(w, u)
+	case "gpx":
+		err = writeGPX(w, u)
+	default:
+		err = fmt.Errorf("unknown format %q", format)
+	}
+	return errors.Join(err, closeFn())
+}
+
+var csvHeader = []string{"dive", "datetime", "divetime", "depth", "temperature"}
+
+func writeCSV(w io.Writer, u *uddf.UDDF) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+
+	format := func(f float64) string { return strconv.FormatFloat(f, 'f', -1, 64) }
+	for _, d := range dives(u) {
+		if d.Samples == nil {
+			continue
+		}
+		start := d.InformationBeforeDive.DateTime.String()
+		for _, wp := range d.Samples.Waypoints {
+			temperature := ""
+			if wp.Temperature != 0 {
+				temperature = format(wp.Temperature)
+			}
+			if err := cw.Write([]string{d.ID, start, format(wp.DiveTime), format(wp.Depth), temperature}); err != nil {
+				return err
+			}
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// readCSV builds a document with one dive per distinct dive column.
+func readCSV(r io.Reader) (*uddf.UDDF, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, errors.New("empty CSV input")
+	}
+
+	columns := map[string]int{}
+	for i, name := range records[0] {
+		columns[strings.TrimSpace(name)] = i
+	}
+	for _, name := range csvHeader[:4] {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("CSV input needs a %q column", name)
+		}
+	}
+
+	type sample struct{ time, depth, temperature float64 }
+	type dive struct {
+		start   time.Time
+		samples []sample
+	}
+	var order []string
+	byID := map[string]*dive{}
+
+	for line, record := range records[1:] {
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		number := func(name string) (float64, error) {
+			s := field(name)
+			if s == "" {
+				return 0, nil
+			}
+			f, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return 0, fmt.Errorf("line %d: invalid %s %q", line+2, name, s)
+			}
+			return f, nil
+		}
+
+		id := field("dive")
+		d, ok := byID[id]
+		if !ok {
+			d = &dive{}
+			if s := field("datetime"); s != "" {
+				t, err := uddf.ParseTime(s)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: invalid datetime %q", line+2, s)
+				}
+				d.start = time.Time(t)
+			}
+			byID[id] = d
+			order = append(order, id)
+		}
+
+		var s sample
+		if s.time, err = number("divetime"); err != nil {
+			return nil, err
+		}
+		if s.depth, err = number("depth"); err != nil {
+			return nil, err
+		}
+		if s.temperature, err = number("temperature"); err != nil {
+			return nil, err
+		}
+		d.samples = append(d.samples, s)
+	}
+
+	b := uddf.New().Generator("uddf", "convert").Owner("", "")
+	for _, id := range order {
+		d := byID[id]
+		b.AddDive(func(db *uddf.DiveBuilder) {
+			db.At(d.start)
+			for _, s := range d.samples {
+				db.Sample(time.Duration(s.time*float64(time.Second)), s.depth)
+			}
+			waypoints := db.Dive().Samples.Waypoints
+			for i, s := range d.samples {
+				waypoints[i].Temperature = s.temperature
+			}
+		})
+	}
+	return b.Build()
+}
+
+type gpx struct {
+	XMLName   xml.Name      `xml:"gpx"`
+	Xmlns     string        `xml:"xmlns,attr,omitempty"`
+	Version   string        `xml:"version,attr"`
+	Creator   string        `xml:"creator,attr"`
+	Waypoints []gpxWaypoint `xml:"wpt"`
+}
+
+type gpxWaypoint struct {
+	Lat  float64  `xml:"lat,attr"`
+	Lon  float64  `xml:"lon,attr"`
+	Ele  *float64 `xml:"ele,omitempty"`
+	Name string   `xml:"name,omitempty"`
+	Desc string   `xml:"desc,omitempty"`
+}
+
+// writeGPX writes every site with coordinates as a waypoint.
+func writeGPX(w io.Writer, u *uddf.UDDF) error {
+	doc := gpx{Xmlns: "http://www.topografix.com/GPX/1/1", Version: "1.1", Creator: "uddf"}
+	if u.DiveSite != nil {
+		for _, site := range u.DiveSite.Sites {
+			g := site.Geography
+			if g == nil || g.Latitude == nil || g.Longitude == nil {
+				continue
+			}
+			doc.Waypoints = append(doc.Waypoints, gpxWaypoint{
+				Lat:  *g.Latitude,
+				Lon:  *g.Longitude,
+				Ele:  g.Altitude,
+				Name: site.Name,
+				Desc: g.Location,
+			})
+		}
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// readGPX builds a document with a site for every waypoint.
+func readGPX(r io.Reader) (*uddf.UDDF, error) {
+	var doc gpx
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to decode GPX: %w", err)
+	}
+
+	b := uddf.New().Generator("uddf", "convert").Owner("", "")
+	for i, wpt := range doc.Waypoints {
+		name := wpt.Name
+		if name == "" {
+			name = fmt.Sprintf("Waypoint %d", i+1)
+		}
+		b.AddSite(name, wpt.Lat, wpt.Lon)
+	}
+
+	u, err := b.Build()
+	if err != nil {
+		return nil, err
+	}
+	for i, wpt := range doc.Waypoints {
+		g := u.DiveSite.Sites[i].Geography
+		g.Altitude = wpt.Ele
+		if wpt.Desc != "" {
+			g.Location = wpt.Desc
+		}
+	}
+	return u, nil
+}
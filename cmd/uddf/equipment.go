@@ -0,0 +1,93 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/Flipez/go-uddf"
+)
+
+type equipmentItem struct {
+	ID                string  `json:"id"`
+	Kind              string  `json:"kind"`
+	Name              string  `json:"name"`
+	Dives             int     `json:"dives"`
+	DiveTime          float64 `json:"dive_time"` // seconds
+	LastUsed          string  `json:"last_used,omitempty"`
+	DueDate           string  `json:"due_date,omitempty"`
+	DivesSinceService int     `json:"dives_since_service"`
+	Status            string  `json:"status"`
+}
+
+func runEquipment(args []string, e *env) int {
+	fs := flag.NewFlagSet("equipment", flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	now := fs.String("now", "", "date to compute the service state for (default: today)")
+	diveInterval := fs.Int("dive-interval", 0, "dives between two services")
+	due := fs.Bool("due", false, "list only items due soon or overdue, and exit with 1 if any is overdue")
+	asJSON := fs.Bool("json", false, "write the list as JSON")
+	fs.Usage = func() {
+		fmt.Fprintln(e.stderr, "Usage: uddf equipment [flags] [file]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+
+	opts := uddf.ServiceOptions{DiveInterval: *diveInterval}
+	var err error
+	if opts.Now, err = parseDate(*now); err != nil {
+		return fail(e, err)
+	}
+	u, err := load(fs.Arg(0), e)
+	if err != nil {
+		return fail(e, err)
+	}
+
+	usage := u.EquipmentUsage(opts)
+	if *due {
+		usage = u.ServiceReminders(opts)
+	}
+
+	code := exitOK
+	items := []equipmentItem{}
+	for _, eu := range usage {
+		item := equipmentItem{
+			ID:                eu.Part.Id,
+			Kind:              string(eu.Kind),
+			Name:              eu.Part.Name,
+			Dives:             eu.Dives,
+			DiveTime:          eu.DiveTime,
+			DivesSinceService: eu.DivesSinceService,
+			Status:            eu.Status.String(),
+		}
+		if !eu.LastUsed.IsZero() {
+			item.LastUsed = eu.LastUsed.Format("2006-01-02")
+		}
+		if !eu.DueDate.IsZero() {
+			item.DueDate = eu.DueDate.Format("2006-01-02")
+		}
+		if *due && eu.Status == uddf.ServiceOverdue {
+			code = exitFailure
+		}
+		items = append(items, item)
+	}
+
+	if *asJSON {
+		if err := writeJSON(e.stdout, items); err != nil {
+			return fail(e, err)
+		}
+		return code
+	}
+
+	tw := tabwriter.NewWriter(e.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tKIND\tNAME\tDIVES\tHOURS\tLAST USED\tDUE\tSTATUS")
+	for _, i := range items {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%.1f\t%s\t%s\t%s\n", i.ID, i.Kind, i.Name, i.Dives, i.DiveTime/3600, i.LastUsed, i.DueDate, i.Status)
+	}
+	if err := tw.Flush(); err != nil {
+		return fail(e, err)
+	}
+	return code
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Flipez/go-uddf"
+)
+
+func runFilter(args []string, e *env) int {
+	fs := flag.NewFlagSet("filter", flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	where := fs.String("where", "", "query predicate relative to the dive, e.g. 'informationafterdive/greatestdepth > 30'")
+	after := fs.String("after", "", "keep dives starting at or after this date (YYYY-MM-DD or RFC3339, local to the dive site without an offset)")
+	before := fs.String("before", "", "keep dives starting before this date (YYYY-MM-DD or RFC3339, local to the dive site without an offset)")
+	minDepth := fs.Float64("min-depth", 0, "keep dives at least this deep in metres")
+	maxDepth := fs.Float64("max-depth", 0, "keep dives at most this deep in metres")
+	site := fs.String("site", "", "keep dives at sites whose name contains this text")
+	out := fs.String("o", "", "output file (default: stdout)")
+	fs.Usage = func() {
+		fmt.Fprintln(e.stderr, "Usage: uddf filter [flags] [file]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+
+	var keep []func(d *uddf.Dive) bool
+	from, err := parseDiveTime(*after)
+	if err != nil {
+		return fail(e, err)
+	}
+	to, err := parseDiveTime(*before)
+	if err != nil {
+		return fail(e, err)
+	}
+	if *minDepth > 0 {
+		keep = append(keep, func(d *uddf.Dive) bool { return d.InformationAfterDive.GreatestDepth >= *minDepth })
+	}
+	if *maxDepth > 0 {
+		keep = append(keep, func(d *uddf.Dive) bool { return d.InformationAfterDive.GreatestDepth <= *maxDepth })
+	}
+
+	u, err := load(fs.Arg(0), e)
+	if err != nil {
+		return fail(e, err)
+	}
+
+	if from != nil || to != nil {
+		keep = append(keep, func(d *uddf.Dive) bool {
+			t := u.DiveTime(d, nil)
+			return (from == nil || !t.Before(from.In(t.Location()))) && (to == nil || t.Before(to.In(t.Location())))
+		})
+	}
+
+	if *where != "" {
+		nodes, err := u.Query("//dive[" + *where + "]")
+		if err != nil {
+			return fail(e, err)
+		}
+		matched := map[*uddf.Dive]bool{}
+		for _, n := range nodes {
+			matched[n.Value.(*uddf.Dive)] = true
+		}
+		keep = append(keep, func(d *uddf.Dive) bool { return matched[d] })
+	}
+	if *site != "" {
+		names := siteNames(u)
+		keep = append(keep, func(d *uddf.Dive) bool {
+			for _, l := range d.InformationBeforeDive.Links {
+				if name, ok := names[l.Ref]; ok && strings.Contains(strings.ToLower(name), strings.ToLower(*site)) {
+					return true
+				}
+			}
+			return false
+		})
+	}
+
+	uddf.Walk(u, uddf.Visitor{OnDive: func(n *uddf.Node, d *uddf.Dive) uddf.WalkAction {
+		for _, k := range keep {
+			if !k(d) {
+				return uddf.Remove
+			}
+		}
+		return uddf.SkipChildren
+	}})
+
+	groups := u.ProfileData.RepetitionGroup[:0]
+	for _, g := range u.ProfileData.RepetitionGroup {
+		if len(g.Dives) > 0 {
+			groups = append(groups, g)
+		}
+	}
+	u.ProfileData.RepetitionGroup = groups
+
+	if err := writeDocument(*out, u, e); err != nil {
+		return fail(e, err)
+	}
+	return exitOK
+}
+
+// parseDate parses a date or a date and time; an empty string yields the
+// zero time.
+func parseDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", s)
+}
+
+// parseDiveTime parses a date or a date and time like parseDate, but yields
+// nil for an empty string. Values without an offset are resolved in the time
+// zone of each dive.
+func parseDiveTime(s string) (*uddf.Time, error) {
+	if s == "" {
+		return nil, nil
+	}
+	t, err := uddf.ParseTime(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date %q", s)
+	}
+	return &t, nil
+}
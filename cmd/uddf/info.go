@@ -0,0 +1,111 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Flipez/go-uddf"
+)
+
+type info struct {
+	Generator string     `json:"generator,omitempty"`
+	Owner     string     `json:"owner"`
+	Dives     int        `json:"dives"`
+	FirstDive *time.Time `json:"first_dive,omitempty"`
+	LastDive  *time.Time `json:"last_dive,omitempty"`
+	Sites     int        `json:"sites"`
+	Mixes     int        `json:"mixes"`
+	Buddies   int        `json:"buddies"`
+}
+
+func runInfo(args []string, e *env) int {
+	fs := flag.NewFlagSet("info", flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	asJSON := fs.Bool("json", false, "write the summary as JSON")
+	fs.Usage = func() {
+		fmt.Fprintln(e.stderr, "Usage: uddf info [flags] [file]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+
+	u, err := load(fs.Arg(0), e)
+	if err != nil {
+		return fail(e, err)
+	}
+	i := summarize(u)
+
+	if *asJSON {
+		if err := writeJSON(e.stdout, i); err != nil {
+			return fail(e, err)
+		}
+		return exitOK
+	}
+
+	if i.Generator != "" {
+		fmt.Fprintf(e.stdout, "Generator: %s\n", i.Generator)
+	}
+	fmt.Fprintf(e.stdout, "Owner:     %s\n", i.Owner)
+	fmt.Fprintf(e.stdout, "Dives:     %d", i.Dives)
+	if i.FirstDive != nil {
+		fmt.Fprintf(e.stdout, " (%s to %s)", i.FirstDive.Format(time.DateOnly), i.LastDive.Format(time.DateOnly))
+	}
+	fmt.Fprintf(e.stdout, "\nSites:     %d\nMixes:     %d\nBuddies:   %d\n", i.Sites, i.Mixes, i.Buddies)
+	return exitOK
+}
+
+func summarize(u *uddf.UDDF) info {
+	i := info{Owner: personName(u.Diver.Owner.Personal), Buddies: len(u.Diver.Buddies)}
+	if g := u.Generator; g != nil {
+		i.Generator = g.Name
+		if g.Version != nil {
+			i.Generator += " " + *g.Version
+		}
+	}
+	if u.DiveSite != nil {
+		i.Sites = len(u.DiveSite.Sites)
+	}
+	if u.GasDefinitions != nil {
+		i.Mixes = len(u.GasDefinitions.Mixes)
+	}
+
+	for _, d := range dives(u) {
+		i.Dives++
+		t := time.Time(d.InformationBeforeDive.DateTime)
+		if t.IsZero() {
+			continue
+		}
+		if i.FirstDive == nil || t.Before(*i.FirstDive) {
+			i.FirstDive = &t
+		}
+		if i.LastDive == nil || t.After(*i.LastDive) {
+			i.LastDive = &t
+		}
+	}
+	return i
+}
+
+func personName(p uddf.Personal) string {
+	var parts []string
+	for _, s := range []*string{p.FirstName, p.LastName} {
+		if s != nil && *s != "" {
+			parts = append(parts, *s)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// dives returns pointers to all dives of u.
+func dives(u *uddf.UDDF) []*uddf.Dive {
+	var result []*uddf.Dive
+	for g := range u.ProfileData.RepetitionGroup {
+		group := &u.ProfileData.RepetitionGroup[g]
+		for i := range group.Dives {
+			result = append(result, &group.Dives[i])
+		}
+	}
+	return result
+}
@@ -0,0 +1,138 @@
+// Command uddf validates, inspects and transforms UDDF documents.
+//
+// Usage:
+//
+//	uddf <command> [flags] [file]
+//
+// Documents are read from the given file, or from stdin if it is missing or
+// "-". Run "uddf help" for the list of commands.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/Flipez/go-uddf"
+)
+
+// Exit codes
+const (
+	exitOK      = 0
+	exitFailure = 1 // the command ran but its check failed, e.g. no matches
+	exitError   = 2 // invalid usage or unreadable input
+)
+
+// env holds the standard streams of a command.
+type env struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+type command struct {
+	summary string
+	run     func(args []string, e *env) int
+}
+
+var commands = map[string]command{
+	"validate":  {"check structure, references and plausibility", runValidate},
+	"info":      {"summarize the diver and the contents", runInfo},
+	"convert":   {"convert between UDDF, JSON, CSV and GPX", runConvert},
+	"merge":     {"merge several documents into one", runMerge},
+	"filter":    {"keep only the dives matching the given criteria", runFilter},
+	"stats":     {"compute dive statistics", runStats},
+	"query":     {"select nodes with an XPath-like expression", runQuery},
+	"profile":   {"draw the depth profile of a dive", runProfile},
+	"report":    {"render a printable HTML logbook page for a dive", runReport},
+	"anonymize": {"remove personal data before sharing a document", runAnonymize},
+	"equipment": {"list equipment usage and service reminders", runEquipment},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], &env{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr}))
+}
+
+func run(args []string, e *env) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		usage(e.stderr)
+		if len(args) == 0 {
+			return exitError
+		}
+		return exitOK
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(e.stderr, "uddf: unknown command %q\n", args[0])
+		usage(e.stderr)
+		return exitError
+	}
+	return cmd.run(args[1:], e)
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: uddf <command> [flags] [file]")
+	fmt.Fprintln(w, "\nCommands:")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-10s %s\n", name, commands[name].summary)
+	}
+}
+
+// load parses the document in path, or stdin if path is empty or "-".
+func load(path string, e *env) (*uddf.UDDF, error) {
+	if path == "" || path == "-" {
+		return uddf.ParseReader(e.stdin)
+	}
+	return uddf.ParseFile(path)
+}
+
+// create opens path for writing, or returns stdout if path is empty or "-".
+// The returned function closes the file.
+func create(path string, e *env) (io.Writer, func() error, error) {
+	if path == "" || path == "-" {
+		return e.stdout, func() error { return nil }, nil
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, f.Close, nil
+}
+
+// writeDocument writes u as UDDF to path, or stdout if path is empty.
+func writeDocument(path string, u *uddf.UDDF, e *env) error {
+	data, err := uddf.Marshal(u)
+	if err != nil {
+		return err
+	}
+	w, closeFn, err := create(path, e)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		closeFn()
+		return err
+	}
+	return closeFn()
+}
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// fail reports err and returns exitError.
+func fail(e *env, err error) int {
+	fmt.Fprintf(e.stderr, "uddf: %v\n", err)
+	return exitError
+}
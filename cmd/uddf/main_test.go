@@ -0,0 +1,381 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Flipez/go-uddf"
+)
+
+const validFile = "../../testdata/valid.uddf"
+
+// runCommand runs the CLI with args and returns the exit code and output.
+func runCommand(t *testing.T, stdin string, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := run(args, &env{stdin: strings.NewReader(stdin), stdout: &stdout, stderr: &stderr})
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRun(t *testing.T) {
+	t.Run("unknown command should fail", func(t *testing.T) {
+		code, _, stderr := runCommand(t, "", "frobnicate")
+		if code != exitError || !strings.Contains(stderr, "unknown command") {
+			t.Errorf("expected usage error, got %d: %s", code, stderr)
+		}
+	})
+}
+
+func TestQueryCommand(t *testing.T) {
+	t.Run("should print matching values with paths", func(t *testing.T) {
+		code, stdout, stderr := runCommand(t, "", "query", "-paths", "//mix[o2 > 0.3]/@id", validFile)
+		if code != exitOK {
+			t.Fatalf("expected exit code 0, got %d: %s", code, stderr)
+		}
+		if want := "/uddf/gasdefinitions/mix[2]/@id\tnitrox32\n"; stdout != want {
+			t.Errorf("expected %q, got %q", want, stdout)
+		}
+	})
+
+	t.Run("should read stdin and print elements as XML", func(t *testing.T) {
+		data, err := os.ReadFile(validFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		code, stdout, _ := runCommand(t, string(data), "query", "//tankdata", "-")
+		if code != exitOK || !strings.HasPrefix(stdout, `<tankdata id="tank1">`) {
+			t.Errorf("expected tankdata element, got %d: %s", code, stdout)
+		}
+	})
+
+	t.Run("no matches should exit with 1", func(t *testing.T) {
+		if code, _, _ := runCommand(t, "", "query", "//buddy", validFile); code != exitFailure {
+			t.Errorf("expected exit code 1, got %d", code)
+		}
+	})
+}
+
+// writeLogbook writes a document with two dives at two sites to a temporary
+// file and returns its path.
+func writeLogbook(t *testing.T) string {
+	t.Helper()
+	start := time.Date(2023, 8, 1, 9, 0, 0, 0, time.UTC)
+	u, err := uddf.New().
+		Owner("Jane", "Diver").
+		AddMix("Air", 0.21, 0).
+		AddSite("Brothers, Egypt", 26.3, 34.8).
+		AddSite("Blue Hole", 17.3, -87.5).
+		AddDive(func(d *uddf.DiveBuilder) {
+			d.At(start).Site("Brothers, Egypt").Tank("Air", 0.012, 200e5, 60e5)
+			d.Sample(0, 0).Sample(20*time.Minute, 32).Sample(45*time.Minute, 0)
+		}).
+		AddDive(func(d *uddf.DiveBuilder) {
+			d.At(start.AddDate(1, 0, 0)).Site("Blue Hole").Tank("Air", 0.012, 200e5, 80e5)
+			d.Sample(0, 0).Sample(15*time.Minute, 12).Sample(55*time.Minute, 0)
+		}).
+		Build()
+	if err != nil {
+		t.Fatalf("failed to build document: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "logbook.uddf")
+	if err := uddf.WriteFile(path, u); err != nil {
+		t.Fatalf("failed to write document: %v", err)
+	}
+	return path
+}
+
+func TestValidateCommand(t *testing.T) {
+	t.Run("valid and invalid files should set the exit code", func(t *testing.T) {
+		code, stdout, _ := runCommand(t, "", "validate", validFile)
+		if code != exitOK || !strings.Contains(stdout, "valid (0 issues)") {
+			t.Errorf("expected valid file, got %d: %s", code, stdout)
+		}
+
+		code, stdout, _ = runCommand(t, "", "validate", validFile, "../../testdata/missing_mix_name.uddf")
+		if code != exitFailure || !strings.Contains(stdout, "missing_mix_name.uddf: invalid") {
+			t.Errorf("expected invalid file, got %d: %s", code, stdout)
+		}
+
+		if code, _, _ := runCommand(t, "", "validate", "does-not-exist.uddf"); code != exitError {
+			t.Errorf("expected exit code 2 for unreadable file, got %d", code)
+		}
+	})
+
+	t.Run("parse errors should be reported as JSON", func(t *testing.T) {
+		code, stdout, _ := runCommand(t, "", "validate", "-json", "../../testdata/invalid_datetime.uddf")
+		if code != exitFailure {
+			t.Errorf("expected exit code 1, got %d", code)
+		}
+		var reports []validateReport
+		if err := json.Unmarshal([]byte(stdout), &reports); err != nil {
+			t.Fatalf("failed to decode report: %v", err)
+		}
+		if len(reports) != 1 || len(reports[0].Issues) != 1 || reports[0].Issues[0].Kind != "parse" {
+			t.Errorf("expected one parse issue, got %+v", reports)
+		}
+	})
+}
+
+func TestInfoAndStatsCommands(t *testing.T) {
+	path := writeLogbook(t)
+
+	code, stdout, stderr := runCommand(t, "", "info", "-json", path)
+	if code != exitOK {
+		t.Fatalf("expected exit code 0, got %d: %s", code, stderr)
+	}
+	var i info
+	if err := json.Unmarshal([]byte(stdout), &i); err != nil {
+		t.Fatalf("failed to decode info: %v", err)
+	}
+	if i.Owner != "Jane Diver" || i.Dives != 2 || i.Sites != 2 || i.Mixes != 1 {
+		t.Errorf("unexpected info %+v", i)
+	}
+
+	code, stdout, _ = runCommand(t, "", "stats", path)
+	if code != exitOK {
+		t.Fatalf("expected exit code 0, got %d", code)
+	}
+	for _, want := range []string{"Dives:              2", "Total dive time:    1h 40min", "Maximum depth:      32.0 m (dive-1)", "2024                           1"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("expected stats to contain %q, got:\n%s", want, stdout)
+		}
+	}
+}
+
+func TestConvertCommand(t *testing.T) {
+	path := writeLogbook(t)
+	dir := t.TempDir()
+
+	t.Run("CSV should round-trip the samples", func(t *testing.T) {
+		csvPath := filepath.Join(dir, "samples.csv")
+		if code, _, stderr := runCommand(t, "", "convert", "-o", csvPath, path); code != exitOK {
+			t.Fatalf("failed to convert to CSV: %s", stderr)
+		}
+		data, err := os.ReadFile(csvPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(data), "dive-1,2023-08-01T09:00:00Z,1200,32,\n") {
+			t.Errorf("unexpected CSV:\n%s", data)
+		}
+
+		code, stdout, stderr := runCommand(t, string(data), "convert", "-from", "csv")
+		if code != exitOK {
+			t.Fatalf("failed to convert from CSV: %s", stderr)
+		}
+		u, err := uddf.Parse([]byte(stdout))
+		if err != nil {
+			t.Fatalf("failed to parse converted document: %v", err)
+		}
+		if n := len(u.ProfileData.RepetitionGroup[0].Dives); n != 2 {
+			t.Errorf("expected 2 dives, got %d", n)
+		}
+		if d := u.ProfileData.RepetitionGroup[0].Dives[1]; d.InformationAfterDive.GreatestDepth != 12 {
+			t.Errorf("expected greatest depth 12 m, got %v", d.InformationAfterDive.GreatestDepth)
+		}
+	})
+
+	t.Run("GPX should round-trip the sites", func(t *testing.T) {
+		code, stdout, _ := runCommand(t, "", "convert", "-to", "gpx", path)
+		if code != exitOK || !strings.Contains(stdout, `<wpt lat="26.3" lon="34.8">`) {
+			t.Fatalf("unexpected GPX %d:\n%s", code, stdout)
+		}
+
+		code, stdout, stderr := runCommand(t, stdout, "convert", "-from", "gpx", "-to", "json")
+		if code != exitOK {
+			t.Fatalf("failed to convert GPX to JSON: %s", stderr)
+		}
+		var u uddf.UDDF
+		if err := json.Unmarshal([]byte(stdout), &u); err != nil {
+			t.Fatalf("failed to decode JSON: %v", err)
+		}
+		if len(u.DiveSite.Sites) != 2 || u.DiveSite.Sites[1].Name != "Blue Hole" {
+			t.Errorf("expected both sites, got %+v", u.DiveSite.Sites)
+		}
+	})
+}
+
+func TestMergeAndFilterCommands(t *testing.T) {
+	path := writeLogbook(t)
+
+	code, stdout, stderr := runCommand(t, "", "merge", path, validFile)
+	if code != exitOK {
+		t.Fatalf("failed to merge: %s", stderr)
+	}
+	merged, err := uddf.Parse([]byte(stdout))
+	if err != nil {
+		t.Fatalf("failed to parse merged document: %v", err)
+	}
+	if n := len(merged.ProfileData.RepetitionGroup); n != 2 {
+		t.Errorf("expected 2 repetition groups, got %d", n)
+	}
+
+	u, err := uddf.ParseFile(path)
+	if err != nil {
+		t.Fatalf("failed to parse document: %v", err)
+	}
+	// 23:30 at the Blue Hole is 05:30 UTC on the next day
+	belize := -6.0
+	u.DiveSite.Sites[1].Geography.TimeZone = &belize
+	u.ProfileData.RepetitionGroup[0].Dives[1].InformationBeforeDive.DateTime = uddf.WallClockTime(time.Date(2024, 7, 31, 23, 30, 0, 0, time.UTC))
+	zoned := filepath.Join(t.TempDir(), "zoned.uddf")
+	if err := uddf.WriteFile(zoned, u); err != nil {
+		t.Fatalf("failed to write document: %v", err)
+	}
+
+	tests := []struct {
+		file     string
+		args     []string
+		expected string
+	}{
+		{path, []string{"-min-depth", "20"}, "dive-1"},
+		{path, []string{"-after", "2024-01-01"}, "dive-2"},
+		{path, []string{"-site", "egypt"}, "dive-1"},
+		{path, []string{"-where", "informationafterdive/diveduration > 3000"}, "dive-2"},
+		{path, []string{"-max-depth", "5"}, ""},
+		{zoned, []string{"-after", "2024-08-01T00:00:00Z"}, "dive-2"},
+		{zoned, []string{"-before", "2024-08-01"}, "dive-1,dive-2"},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			code, stdout, stderr := runCommand(t, "", append(append([]string{"filter"}, tt.args...), tt.file)...)
+			if code != exitOK {
+				t.Fatalf("failed to filter: %s", stderr)
+			}
+			u, err := uddf.Parse([]byte(stdout))
+			if err != nil {
+				t.Fatalf("failed to parse filtered document: %v", err)
+			}
+			var ids []string
+			for _, g := range u.ProfileData.RepetitionGroup {
+				for _, d := range g.Dives {
+					ids = append(ids, d.ID)
+				}
+			}
+			if got := strings.Join(ids, ","); got != tt.expected {
+				t.Errorf("expected dives %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestProfileCommand(t *testing.T) {
+	path := writeLogbook(t)
+
+	t.Run("should select dives by number and ID", func(t *testing.T) {
+		code, stdout, stderr := runCommand(t, "", "profile", "-dive", "2", "-ascii", path)
+		if code != exitOK {
+			t.Fatalf("expected exit code 0, got %d: %s", code, stderr)
+		}
+		if !strings.HasPrefix(stdout, "dive-2 2024-08-01 09:00 Blue Hole\n") || !strings.Contains(stdout, "   12 m |") {
+			t.Errorf("unexpected profile:\n%s", stdout)
+		}
+
+		code, stdout, _ = runCommand(t, "", "profile", "-dive", "dive-1", "-format", "sparkline", "-width", "10", path)
+		if code != exitOK || len([]rune(strings.TrimSpace(stdout))) != 10 {
+			t.Errorf("expected a sparkline of 10 runes, got %d: %q", code, stdout)
+		}
+	})
+
+	t.Run("should write SVG", func(t *testing.T) {
+		code, stdout, _ := runCommand(t, "", "profile", "-format", "svg", path)
+		if code != exitOK || !strings.HasPrefix(stdout, "<svg ") {
+			t.Errorf("expected SVG, got %d: %.40s", code, stdout)
+		}
+	})
+
+	t.Run("unknown dives and formats should fail", func(t *testing.T) {
+		if code, _, _ := runCommand(t, "", "profile", "-dive", "3", path); code != exitError {
+			t.Errorf("expected exit code 2 for unknown dive, got %d", code)
+		}
+		if code, _, _ := runCommand(t, "", "profile", "-format", "pdf", path); code != exitError {
+			t.Errorf("expected exit code 2 for unknown format, got %d", code)
+		}
+	})
+
+	t.Run("dives without samples should fail", func(t *testing.T) {
+		code, stdout, stderr := runCommand(t, "", "profile", validFile)
+		if code != exitFailure || stdout != "" || !strings.Contains(stderr, "no samples") {
+			t.Errorf("expected exit code 1 and no chart, got %d: %q %q", code, stdout, stderr)
+		}
+	})
+}
+
+func TestReportCommand(t *testing.T) {
+	path := writeLogbook(t)
+
+	code, stdout, stderr := runCommand(t, "", "report", "-dive", "dive-2", path)
+	if code != exitOK {
+		t.Fatalf("expected exit code 0, got %d: %s", code, stderr)
+	}
+	if !strings.Contains(stdout, "<title>Dive 2 – Blue Hole</title>") {
+		t.Errorf("unexpected report:\n%s", stdout)
+	}
+
+	tmpl := filepath.Join(t.TempDir(), "page.html")
+	if err := os.WriteFile(tmpl, []byte(`{{.Diver}}: {{.Site.Name}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	code, stdout, _ = runCommand(t, "", "report", "-template", tmpl, path)
+	if code != exitOK || stdout != "Jane Diver: Brothers, Egypt" {
+		t.Errorf("expected custom template output, got %d: %q", code, stdout)
+	}
+}
+
+func TestAnonymizeCommand(t *testing.T) {
+	path := writeLogbook(t)
+
+	code, stdout, stderr := runCommand(t, "", "anonymize", "-key", "secret", "-keep-dates", path)
+	if code != exitOK {
+		t.Fatalf("expected exit code 0, got %d: %s", code, stderr)
+	}
+	u, err := uddf.Parse([]byte(stdout))
+	if err != nil {
+		t.Fatalf("failed to parse anonymized document: %v", err)
+	}
+	if name := *u.Diver.Owner.Personal.LastName; name == "Diver" {
+		t.Errorf("expected a pseudonym, got %q", name)
+	}
+	if got := time.Time(u.ProfileData.RepetitionGroup[0].Dives[0].InformationBeforeDive.DateTime); got.Year() != 2023 {
+		t.Errorf("expected dates to be kept, got %v", got)
+	}
+}
+
+func TestEquipmentCommand(t *testing.T) {
+	path := writeLogbook(t)
+	u, err := uddf.ParseFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.Diver.Owner.Equipment = &uddf.Equipment{}
+	u.Diver.Owner.Equipment.Regulators = []uddf.EquipmentPart{{Id: "reg-1", Name: "Primary"}}
+	for i := range u.ProfileData.RepetitionGroup[0].Dives {
+		u.ProfileData.RepetitionGroup[0].Dives[i].InformationAfterDive.EquipmentUsed = &uddf.EquipmentUsed{Links: []uddf.Link{{Ref: "reg-1"}}}
+	}
+	if err := uddf.WriteFile(path, u); err != nil {
+		t.Fatal(err)
+	}
+
+	code, stdout, stderr := runCommand(t, "", "equipment", path)
+	if code != exitOK {
+		t.Fatalf("expected exit code 0, got %d: %s", code, stderr)
+	}
+	if !strings.Contains(stdout, "reg-1  regulator  Primary  2      1.7    2024-08-01       unknown") {
+		t.Errorf("unexpected list:\n%s", stdout)
+	}
+
+	code, stdout, _ = runCommand(t, "", "equipment", "-due", "-dive-interval", "2", "-json", path)
+	var items []equipmentItem
+	if err := json.Unmarshal([]byte(stdout), &items); err != nil {
+		t.Fatalf("failed to decode list: %v", err)
+	}
+	if code != exitFailure || len(items) != 1 || items[0].Status != "overdue" {
+		t.Errorf("expected the regulator to be overdue, got %d: %+v", code, items)
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/Flipez/go-uddf"
+)
+
+func runMerge(args []string, e *env) int {
+	fs := flag.NewFlagSet("merge", flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	out := fs.String("o", "", "output file (default: stdout)")
+	fs.Usage = func() {
+		fmt.Fprintln(e.stderr, "Usage: uddf merge [flags] file...")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitError
+	}
+
+	docs := make([]*uddf.UDDF, fs.NArg())
+	for i, file := range fs.Args() {
+		u, err := load(file, e)
+		if err != nil {
+			return fail(e, err)
+		}
+		docs[i] = u
+	}
+
+	merged, err := uddf.Merge(docs...)
+	if err != nil {
+		return fail(e, err)
+	}
+	if err := writeDocument(*out, merged, e); err != nil {
+		return fail(e, err)
+	}
+	return exitOK
+}
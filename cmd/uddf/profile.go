@@ -0,0 +1,84 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+
+	"github.com/Flipez/go-uddf"
+)
+
+func runProfile(args []string, e *env) int {
+	fs := flag.NewFlagSet("profile", flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	dive := fs.String("dive", "", "ID or 1-based number of the dive (default: the first)")
+	format := fs.String("format", "text", "output format: text, sparkline, svg or png")
+	width := fs.Int("width", 0, "width in characters or pixels")
+	height := fs.Int("height", 0, "height in characters or pixels")
+	ascii := fs.Bool("ascii", false, "use only ASCII characters for text output")
+	out := fs.String("o", "", "output file (default: stdout)")
+	fs.Usage = func() {
+		fmt.Fprintln(e.stderr, "Usage: uddf profile [flags] [file]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+
+	u, err := load(fs.Arg(0), e)
+	if err != nil {
+		return fail(e, err)
+	}
+	d, err := findDive(u, *dive)
+	if err != nil {
+		return fail(e, err)
+	}
+	if d.Samples == nil || len(d.Samples.Waypoints) == 0 {
+		fmt.Fprintf(e.stderr, "uddf: %s: no samples\n", d.ID)
+		return exitFailure
+	}
+
+	w, closeFn, err := create(*out, e)
+	if err != nil {
+		return fail(e, err)
+	}
+	chart := u.Chart(d)
+	opts := uddf.ChartOptions{Width: *width, Height: *height, ASCII: *ascii}
+	switch *format {
+	case "text":
+		err = chart.WriteText(w, opts)
+	case "sparkline":
+		_, err = fmt.Fprintln(w, chart.Sparkline(*width))
+	case "svg":
+		err = chart.WriteSVG(w, opts)
+	case "png":
+		err = chart.WritePNG(w, opts)
+	default:
+		err = fmt.Errorf("unknown format %q", *format)
+	}
+	if cerr := closeFn(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fail(e, err)
+	}
+	return exitOK
+}
+
+// findDive returns the dive with the given ID or 1-based number, or the
+// first dive if key is empty.
+func findDive(u *uddf.UDDF, key string) (*uddf.Dive, error) {
+	all := dives(u)
+	if key == "" {
+		key = "1"
+	}
+	for _, d := range all {
+		if d.ID == key {
+			return d, nil
+		}
+	}
+	if n, err := strconv.Atoi(key); err == nil && n >= 1 && n <= len(all) {
+		return all[n-1], nil
+	}
+	return nil, fmt.Errorf("unknown dive %q", key)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/Flipez/go-uddf"
+)
+
+func runQuery(args []string, e *env) int {
+	fs := flag.NewFlagSet("query", flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	paths := fs.Bool("paths", false, "prefix every result with its path")
+	asJSON := fs.Bool("json", false, "write results as JSON")
+	fs.Usage = func() {
+		fmt.Fprintln(e.stderr, "Usage: uddf query [flags] <expression> [file]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		fs.Usage()
+		return exitError
+	}
+
+	q, err := uddf.CompileQuery(fs.Arg(0))
+	if err != nil {
+		return fail(e, err)
+	}
+	u, err := load(fs.Arg(1), e)
+	if err != nil {
+		return fail(e, err)
+	}
+
+	nodes := q.Select(u)
+	if *asJSON {
+		err = writeNodesJSON(e.stdout, nodes)
+	} else {
+		err = writeNodes(e.stdout, nodes, *paths)
+	}
+	if err != nil {
+		return fail(e, err)
+	}
+
+	if len(nodes) == 0 {
+		return exitFailure
+	}
+	return exitOK
+}
+
+// writeNodes prints the text of simple values and the XML of elements with
+// children.
+func writeNodes(w io.Writer, nodes []*uddf.Node, paths bool) error {
+	for _, n := range nodes {
+		if paths {
+			fmt.Fprintf(w, "%s\t", n.Path)
+		}
+		if !isElement(n) {
+			if _, err := fmt.Fprintln(w, n.Text()); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if paths {
+			fmt.Fprintln(w)
+		}
+		enc := xml.NewEncoder(w)
+		enc.Indent("", "  ")
+		if err := enc.EncodeElement(n.Value, xml.StartElement{Name: xml.Name{Local: n.Name}}); err != nil {
+			return fmt.Errorf("failed to encode %s: %w", n.Path, err)
+		}
+		if _, err := fmt.Fprintln(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeNodesJSON(w io.Writer, nodes []*uddf.Node) error {
+	type result struct {
+		Path  string `json:"path"`
+		Value any    `json:"value"`
+	}
+	results := make([]result, len(nodes))
+	for i, n := range nodes {
+		results[i] = result{Path: n.Path, Value: n.Value}
+		if !isElement(n) {
+			results[i].Value = n.Text()
+		}
+	}
+
+	return writeJSON(w, results)
+}
+
+// isElement reports whether the node is an element with children rather
+// than a simple value.
+func isElement(n *uddf.Node) bool {
+	switch n.Value.(type) {
+	case *uddf.Time, *uddf.FlexibleFloat:
+		return false
+	}
+	v := reflect.ValueOf(n.Value)
+	return v.Kind() == reflect.Pointer && v.Elem().Kind() == reflect.Struct
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"html/template"
+	"os"
+
+	"github.com/Flipez/go-uddf"
+)
+
+func runReport(args []string, e *env) int {
+	fs := flag.NewFlagSet("report", flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	dive := fs.String("dive", "", "ID or 1-based number of the dive (default: the first)")
+	templateFile := fs.String("template", "", "HTML template replacing the default one")
+	out := fs.String("o", "", "output file (default: stdout)")
+	fs.Usage = func() {
+		fmt.Fprintln(e.stderr, "Usage: uddf report [flags] [file]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+
+	var tmpl *template.Template
+	if *templateFile != "" {
+		text, err := os.ReadFile(*templateFile)
+		if err != nil {
+			return fail(e, err)
+		}
+		if tmpl, err = uddf.ParseReportTemplate(string(text)); err != nil {
+			return fail(e, err)
+		}
+	}
+
+	u, err := load(fs.Arg(0), e)
+	if err != nil {
+		return fail(e, err)
+	}
+	d, err := findDive(u, *dive)
+	if err != nil {
+		return fail(e, err)
+	}
+
+	w, closeFn, err := create(*out, e)
+	if err != nil {
+		return fail(e, err)
+	}
+	err = u.Report(d).WriteHTML(w, tmpl)
+	if cerr := closeFn(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fail(e, err)
+	}
+	return exitOK
+}
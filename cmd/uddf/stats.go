@@ -0,0 +1,148 @@
+package main
+
+import (
+	"cmp"
+	"flag"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/Flipez/go-uddf"
+)
+
+// kelvin is 0 °C in Kelvin, the unit of UDDF temperatures.
+const kelvin = 273.15
+
+type stats struct {
+	Dives             int            `json:"dives"`
+	TotalDiveTime     float64        `json:"total_dive_time"`    // seconds
+	AverageDiveTime   float64        `json:"average_dive_time"`  // seconds
+	MaximumDepth      float64        `json:"maximum_depth"`      // metres
+	DeepestDive       string         `json:"deepest_dive"`       // dive ID
+	AverageDepth      float64        `json:"average_depth"`      // mean of the greatest depths in metres
+	LongestDive       string         `json:"longest_dive"`       // dive ID
+	LowestTemperature *float64       `json:"lowest_temperature"` // °C
+	DivesPerYear      map[string]int `json:"dives_per_year"`
+	DivesPerSite      map[string]int `json:"dives_per_site"`
+}
+
+func runStats(args []string, e *env) int {
+	fs := flag.NewFlagSet("stats", flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	asJSON := fs.Bool("json", false, "write the statistics as JSON")
+	fs.Usage = func() {
+		fmt.Fprintln(e.stderr, "Usage: uddf stats [flags] [file]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+
+	u, err := load(fs.Arg(0), e)
+	if err != nil {
+		return fail(e, err)
+	}
+	s := computeStats(u)
+
+	if *asJSON {
+		if err := writeJSON(e.stdout, s); err != nil {
+			return fail(e, err)
+		}
+		return exitOK
+	}
+
+	fmt.Fprintf(e.stdout, "Dives:              %d\n", s.Dives)
+	if s.Dives == 0 {
+		return exitOK
+	}
+	fmt.Fprintf(e.stdout, "Total dive time:    %s\n", formatDuration(s.TotalDiveTime))
+	fmt.Fprintf(e.stdout, "Average dive time:  %s\n", formatDuration(s.AverageDiveTime))
+	fmt.Fprintf(e.stdout, "Maximum depth:      %.1f m (%s)\n", s.MaximumDepth, s.DeepestDive)
+	fmt.Fprintf(e.stdout, "Average depth:      %.1f m\n", s.AverageDepth)
+	fmt.Fprintf(e.stdout, "Longest dive:       %s\n", s.LongestDive)
+	if s.LowestTemperature != nil {
+		fmt.Fprintf(e.stdout, "Lowest temperature: %.1f °C\n", *s.LowestTemperature)
+	}
+	writeCounts(e, "Dives per year", s.DivesPerYear, false)
+	writeCounts(e, "Dives per site", s.DivesPerSite, true)
+	return exitOK
+}
+
+func computeStats(u *uddf.UDDF) stats {
+	s := stats{DivesPerYear: map[string]int{}, DivesPerSite: map[string]int{}}
+	sites := siteNames(u)
+
+	var longest, depths float64
+	for _, d := range dives(u) {
+		s.Dives++
+		after := d.InformationAfterDive
+		s.TotalDiveTime += after.DiveDuration
+		depths += after.GreatestDepth
+		if after.GreatestDepth > s.MaximumDepth {
+			s.MaximumDepth, s.DeepestDive = after.GreatestDepth, d.ID
+		}
+		if after.DiveDuration > longest {
+			longest, s.LongestDive = after.DiveDuration, d.ID
+		}
+		if after.LowestTemperature != nil {
+			celsius := *after.LowestTemperature - kelvin
+			if s.LowestTemperature == nil || celsius < *s.LowestTemperature {
+				s.LowestTemperature = &celsius
+			}
+		}
+
+		if t := time.Time(d.InformationBeforeDive.DateTime); !t.IsZero() {
+			s.DivesPerYear[fmt.Sprint(t.Year())]++
+		}
+		for _, l := range d.InformationBeforeDive.Links {
+			if name, ok := sites[l.Ref]; ok {
+				s.DivesPerSite[name]++
+			}
+		}
+	}
+
+	if s.Dives > 0 {
+		s.AverageDiveTime = s.TotalDiveTime / float64(s.Dives)
+		s.AverageDepth = depths / float64(s.Dives)
+	}
+	return s
+}
+
+// siteNames maps the IDs of the sites of u to their names.
+func siteNames(u *uddf.UDDF) map[string]string {
+	names := map[string]string{}
+	if u.DiveSite != nil {
+		for _, site := range u.DiveSite.Sites {
+			names[site.ID] = site.Name
+		}
+	}
+	return names
+}
+
+// writeCounts prints counts sorted by key, or by count if byCount is set.
+func writeCounts(e *env, title string, counts map[string]int, byCount bool) {
+	if len(counts) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	slices.SortFunc(keys, func(a, b string) int {
+		if byCount && counts[a] != counts[b] {
+			return counts[b] - counts[a]
+		}
+		return cmp.Compare(a, b)
+	})
+
+	fmt.Fprintf(e.stdout, "%s:\n", title)
+	for _, k := range keys {
+		fmt.Fprintf(e.stdout, "  %-30s %d\n", k, counts[k])
+	}
+}
+
+func formatDuration(seconds float64) string {
+	minutes := int(math.Round(seconds / 60))
+	return fmt.Sprintf("%dh %02dmin", minutes/60, minutes%60)
+}
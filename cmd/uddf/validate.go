@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/Flipez/go-uddf"
+)
+
+type validateReport struct {
+	File   string       `json:"file"`
+	Valid  bool         `json:"valid"`
+	Issues []uddf.Issue `json:"issues"`
+}
+
+// runValidate checks every given document. It exits with 1 if any document
+// has errors, or warnings with -strict.
+func runValidate(args []string, e *env) int {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	asJSON := fs.Bool("json", false, "write the report as JSON")
+	strict := fs.Bool("strict", false, "treat warnings as errors")
+	fs.Usage = func() {
+		fmt.Fprintln(e.stderr, "Usage: uddf validate [flags] [file...]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+
+	files := fs.Args()
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+
+	code := exitOK
+	var reports []validateReport
+	for _, file := range files {
+		report := validateReport{File: file, Issues: []uddf.Issue{}}
+		u, err := load(file, e)
+		var parseErr *uddf.ParseError
+		switch {
+		case errors.As(err, &parseErr):
+			report.Issues = append(report.Issues, parseIssue(parseErr))
+		case err != nil:
+			fmt.Fprintf(e.stderr, "uddf: %v\n", err)
+			code = exitError
+			continue
+		default:
+			report.Issues = append(report.Issues, u.Check()...)
+		}
+
+		report.Valid = !uddf.HasErrors(report.Issues) && !(*strict && len(report.Issues) > 0)
+		if !report.Valid && code == exitOK {
+			code = exitFailure
+		}
+		reports = append(reports, report)
+	}
+
+	if *asJSON {
+		if err := writeJSON(e.stdout, reports); err != nil {
+			return fail(e, err)
+		}
+		return code
+	}
+
+	for _, r := range reports {
+		for _, issue := range r.Issues {
+			fmt.Fprintf(e.stdout, "%s: %s\n", r.File, issue)
+		}
+		status := "valid"
+		if !r.Valid {
+			status = "invalid"
+		}
+		fmt.Fprintf(e.stdout, "%s: %s (%d issues)\n", r.File, status, len(r.Issues))
+	}
+	return code
+}
+
+func parseIssue(pe *uddf.ParseError) uddf.Issue {
+	message := fmt.Sprintf("line %d, column %d", pe.Line, pe.Column)
+	if pe.RawValue != "" {
+		message += fmt.Sprintf(": invalid value %q", pe.RawValue)
+	}
+	if pe.Err != nil {
+		message += ": " + pe.Err.Error()
+	}
+	return uddf.Issue{Severity: uddf.SeverityError, Kind: "parse", Path: pe.Path, Message: message}
+}
@@ -0,0 +1,140 @@
+package uddf
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// ErrNoDecompressor is returned when input is recognised as compressed but
+// no decompressor has been registered for its format.
+var ErrNoDecompressor = errors.New("no decompressor registered")
+
+var (
+	magicGzip = []byte{0x1F, 0x8B}
+	magicZstd = []byte{0x28, 0xB5, 0x2F, 0xFD}
+	magicZip  = []byte{'P', 'K', 0x03, 0x04}
+)
+
+type decompressor struct {
+	name  string
+	magic []byte
+	fn    func(io.Reader) (io.Reader, error)
+}
+
+var (
+	decompressorsMu sync.RWMutex
+	decompressors   = []decompressor{
+		{name: "gzip", magic: magicGzip, fn: func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) }},
+		{name: "zstd", magic: magicZstd, fn: func(r io.Reader) (io.Reader, error) {
+			d, err := zstd.NewReader(r)
+			if err != nil {
+				return nil, err
+			}
+			return d.IOReadCloser(), nil
+		}},
+	}
+)
+
+// RegisterDecompressor registers a decompressor for input starting with the
+// given magic bytes. It replaces any decompressor previously registered under
+// the same name, such as the built-in "gzip" and "zstd" ones:
+//
+//	uddf.RegisterDecompressor("xz", []byte{0xFD, '7', 'z', 'X', 'Z', 0x00}, func(r io.Reader) (io.Reader, error) {
+//		return xz.NewReader(r)
+//	})
+//
+// A nil fn registers the format without a decoder, so that matching input
+// fails with ErrNoDecompressor instead of being parsed as plain XML.
+func RegisterDecompressor(name string, magic []byte, fn func(io.Reader) (io.Reader, error)) {
+	decompressorsMu.Lock()
+	defer decompressorsMu.Unlock()
+
+	for i, d := range decompressors {
+		if d.name == name {
+			decompressors[i] = decompressor{name: name, magic: magic, fn: fn}
+			return
+		}
+	}
+	decompressors = append(decompressors, decompressor{name: name, magic: magic, fn: fn})
+}
+
+// unpack sniffs data for compression and archive magic bytes and returns the
+// plain UDDF document. Data that is not recognised is returned unchanged.
+func unpack(data []byte) ([]byte, error) {
+	if bytes.HasPrefix(data, magicZip) {
+		return unpackZip(data)
+	}
+
+	decompressorsMu.RLock()
+	var match *decompressor
+	for _, d := range decompressors {
+		if len(d.magic) > 0 && bytes.HasPrefix(data, d.magic) {
+			match = &d
+			break
+		}
+	}
+	decompressorsMu.RUnlock()
+
+	if match == nil {
+		return data, nil
+	}
+	if match.fn == nil {
+		return nil, fmt.Errorf("failed to decompress %s input: %w", match.name, ErrNoDecompressor)
+	}
+
+	r, err := match.fn(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress %s input: %w", match.name, err)
+	}
+	if c, ok := r.(io.Closer); ok {
+		defer c.Close()
+	}
+
+	plain, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress %s input: %w", match.name, err)
+	}
+
+	// Archives are commonly compressed as a whole (e.g. .uddf.zip.gz)
+	return unpack(plain)
+}
+
+func unpackZip(data []byte) ([]byte, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open zip archive: %w", err)
+	}
+
+	f, err := findDocument(zr)
+	if err != nil {
+		return nil, err
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s in zip archive: %w", f.Name, err)
+	}
+	defer rc.Close()
+
+	return io.ReadAll(rc)
+}
+
+// findDocument returns the UDDF document of a zip archive, which is the
+// first entry with a .uddf extension.
+func findDocument(zr *zip.Reader) (*zip.File, error) {
+	for _, f := range zr.File {
+		if strings.EqualFold(path.Ext(f.Name), ".uddf") {
+			return f, nil
+		}
+	}
+	return nil, fmt.Errorf("zip archive contains no .uddf file")
+}
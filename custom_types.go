@@ -1,6 +1,7 @@
 package uddf
 
 import (
+	"encoding/json"
 	"encoding/xml"
 	"fmt"
 	"log"
@@ -35,27 +36,179 @@ func (f *FlexibleFloat) UnmarshalXML(d *xml.Decoder, start xml.StartElement) err
 	return nil
 }
 
+func (f FlexibleFloat) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if f.Value == nil {
+		return nil
+	}
+	return e.EncodeElement(strconv.FormatFloat(*f.Value, 'f', -1, 64), start)
+}
+
+// Time is a point in time as written in a document. Values given without a
+// UTC offset, as dive computers commonly record them, are wall-clock times:
+// they are held as UTC with the same clock reading, are resolved with In and
+// are written back without an offset. Time also records the precision it
+// was given with, so that "2019" is written back as such and not as
+// "2019-01-01T00:00:00Z".
 type Time time.Time
 
+// TimePrecision is the precision a Time was given with.
+type TimePrecision int
+
+const (
+	PrecisionOffset TimePrecision = iota // date and time with a UTC offset
+	PrecisionSecond                      // date and time to the second, no offset
+	PrecisionMinute                      // date and time to the minute, no offset
+	PrecisionDate                        // date only
+	PrecisionYear                        // year only
+)
+
+var precisionNames = []string{"offset", "second", "minute", "date", "year"}
+
+func (p TimePrecision) String() string {
+	if p < 0 || int(p) >= len(precisionNames) {
+		return fmt.Sprintf("TimePrecision(%d)", int(p))
+	}
+	return precisionNames[p]
+}
+
+// timeFormats are the accepted layouts, tried in order.
+var timeFormats = []struct {
+	layout    string
+	precision TimePrecision
+}{
+	{time.RFC3339, PrecisionOffset},           // 2006-01-02T15:04:05Z07:00
+	{"2006-01-02", PrecisionDate},             // YYYY-MM-DD
+	{"2006-01-02T15:04:05Z", PrecisionOffset}, // ISO 8601 UTC
+	{"2006-01-02T15:04:05", PrecisionSecond},  // ISO 8601 without timezone
+	{"2006-01-02T15:04", PrecisionMinute},     // YYYY-MM-DDTHH:MM (without seconds)
+	{"2006", PrecisionYear},                   // YYYY (year only)
+}
+
+// precisionLayouts are the layouts times are written with.
+var precisionLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02", "2006"}
+
+// precisionZones mark times given without an offset by their precision.
+// They are all at UTC, so that such times compare by their clock reading,
+// and named, as unnamed zones are shared with parsed "+00:00" offsets.
+var precisionZones = map[TimePrecision]*time.Location{
+	PrecisionSecond: time.FixedZone("local", 0),
+	PrecisionMinute: time.FixedZone("local", 0),
+	PrecisionDate:   time.FixedZone("local", 0),
+	PrecisionYear:   time.FixedZone("local", 0),
+}
+
+// numericUTC marks times given with a "+00:00" offset rather than "Z", so
+// that they are written back the same way.
+var numericUTC = time.FixedZone("+00:00", 0)
+
+// WallClockTime returns the clock reading of t as a Time without an offset.
+func WallClockTime(t time.Time) Time {
+	return Time(time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), precisionZones[PrecisionSecond]))
+}
+
+// Precision returns the precision t was given with.
+func (t Time) Precision() TimePrecision {
+	loc := time.Time(t).Location()
+	for p, zone := range precisionZones {
+		if loc == zone {
+			return p
+		}
+	}
+	return PrecisionOffset
+}
+
+// WithPrecision returns t truncated to p. Times without an offset keep
+// their clock reading; PrecisionOffset turns it into UTC.
+func (t Time) WithPrecision(p TimePrecision) Time {
+	tt := time.Time(t)
+	if p == PrecisionOffset {
+		if !t.HasOffset() {
+			return Time(time.Date(tt.Year(), tt.Month(), tt.Day(), tt.Hour(), tt.Minute(), tt.Second(), tt.Nanosecond(), time.UTC))
+		}
+		return t
+	}
+
+	year, month, day := tt.Date()
+	hour, minute, sec, nsec := tt.Hour(), tt.Minute(), tt.Second(), tt.Nanosecond()
+	switch p {
+	case PrecisionYear:
+		month, day = time.January, 1
+		fallthrough
+	case PrecisionDate:
+		hour, minute = 0, 0
+		fallthrough
+	case PrecisionMinute:
+		sec, nsec = 0, 0
+	}
+	return Time(time.Date(year, month, day, hour, minute, sec, nsec, precisionZones[p]))
+}
+
+// HasOffset reports whether t was given with a UTC offset.
+func (t Time) HasOffset() bool {
+	return t.Precision() == PrecisionOffset
+}
+
+// In returns the instant t denotes, in loc: a wall-clock time is taken as
+// local time in loc, other times are converted to loc. A nil loc stands for
+// UTC.
+func (t Time) In(loc *time.Location) time.Time {
+	if loc == nil {
+		loc = time.UTC
+	}
+	tt := time.Time(t)
+	if t.HasOffset() {
+		return tt.In(loc)
+	}
+	return time.Date(tt.Year(), tt.Month(), tt.Day(), tt.Hour(), tt.Minute(), tt.Second(), tt.Nanosecond(), loc)
+}
+
+// UTC returns the instant t denotes in UTC, taking a wall-clock time as
+// local time in loc.
+func (t Time) UTC(loc *time.Location) time.Time {
+	return t.In(loc).UTC()
+}
+
+// String returns t as written to documents, in the layout and with the
+// precision it was given with.
+func (t Time) String() string {
+	if time.Time(t).Location() == numericUTC {
+		return time.Time(t).Format("2006-01-02T15:04:05.999999999-07:00")
+	}
+	return time.Time(t).Format(precisionLayouts[t.Precision()])
+}
+
+// display returns t for people to read, without parts it was not given
+// with.
+func (t Time) display() string {
+	switch t.Precision() {
+	case PrecisionYear:
+		return time.Time(t).Format("2006")
+	case PrecisionDate:
+		return time.Time(t).Format("2006-01-02")
+	}
+	return time.Time(t).Format("2006-01-02 15:04")
+}
+
+// ParseTime parses s in any of the layouts accepted in documents.
+func ParseTime(s string) (Time, error) {
+	var t Time
+	err := t.parseTimeString(s)
+	return t, err
+}
+
 func (t *Time) parseTimeString(dateStr string) error {
 	// Trim any whitespace
 	dateStr = strings.TrimSpace(dateStr)
 
-	// Try to parse the date in multiple formats
-	formats := []string{
-		time.RFC3339,           // 2006-01-02T15:04:05Z07:00
-		"2006-01-02",           // YYYY-MM-DD
-		"2006-01-02T15:04:05Z", // ISO 8601 UTC
-		"2006-01-02T15:04:05",  // ISO 8601 UTC without timezone
-		"2006-01-02T15:04",     // YYYY-MM-DDTHH:MM (without seconds)
-		"2006",                 // YYYY (year only)
-	}
-
 	var parsedTime time.Time
 	var err error
 
-	for _, format := range formats {
-		parsedTime, err = time.Parse(format, dateStr)
+	for _, format := range timeFormats {
+		loc := time.UTC
+		if zone, ok := precisionZones[format.precision]; ok {
+			loc = zone
+		}
+		parsedTime, err = time.ParseInLocation(format.layout, dateStr, loc)
 		if err == nil {
 			break
 		}
@@ -64,19 +217,50 @@ func (t *Time) parseTimeString(dateStr string) error {
 	if err != nil {
 		return fmt.Errorf("unable to parse datetime '%s': %w", dateStr, err)
 	}
+	if strings.HasSuffix(dateStr, "+00:00") {
+		parsedTime = parsedTime.In(numericUTC)
+	}
 
 	*t = Time(parsedTime)
 	return nil
 }
 
 func (t *Time) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	line, column := d.InputPos()
+
 	var dateStr string
 	if err := d.DecodeElement(&dateStr, &start); err != nil {
 		return err
 	}
-	return t.parseTimeString(dateStr)
+	if err := t.parseTimeString(dateStr); err != nil {
+		return &ParseError{Line: line, Column: column, RawValue: dateStr, Err: err}
+	}
+	return nil
 }
 
 func (t *Time) UnmarshalXMLAttr(attr xml.Attr) error {
-	return t.parseTimeString(attr.Value)
+	if err := t.parseTimeString(attr.Value); err != nil {
+		return &ParseError{Path: "@" + attr.Name.Local, RawValue: attr.Value, Err: err}
+	}
+	return nil
+}
+
+func (t Time) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(t.String(), start)
+}
+
+func (t Time) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
+	return xml.Attr{Name: name, Value: t.String()}, nil
+}
+
+func (t Time) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.String())
+}
+
+func (t *Time) UnmarshalJSON(data []byte) error {
+	var dateStr string
+	if err := json.Unmarshal(data, &dateStr); err != nil {
+		return err
+	}
+	return t.parseTimeString(dateStr)
 }
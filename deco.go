@@ -0,0 +1,179 @@
+package uddf
+
+import (
+	"math"
+	"slices"
+)
+
+const (
+	gravity             = 9.80665 // m/s^2
+	pascalPerBar        = 1e5
+	seaLevelPressure    = 1.01325 // bar
+	waterVapourPressure = 0.0627  // bar, alveolar water vapour pressure at 37 °C
+	airN2Fraction       = 0.7902
+	zeroCelsius         = 273.15 // K
+)
+
+// celsius converts a temperature in Kelvin, the unit of UDDF, to °C.
+func celsius(kelvin float64) float64 {
+	return kelvin - zeroCelsius
+}
+
+// Compartment holds the parameters of a single Bühlmann tissue compartment.
+// Half-lives are given in seconds, a values in bar.
+type Compartment struct {
+	N2HalfLife float64
+	N2A        float64
+	N2B        float64
+	HeHalfLife float64
+	HeA        float64
+	HeB        float64
+}
+
+// ZHL16C contains the compartments of the Bühlmann ZH-L16C model, using
+// compartment 1b for the fastest tissue. It is used whenever a Buehlmann
+// parameter set does not list its own tissues.
+var ZHL16C = []Compartment{
+	{5.0 * 60, 1.1696, 0.5578, 1.88 * 60, 1.6189, 0.4770},
+	{8.0 * 60, 1.0000, 0.6514, 3.02 * 60, 1.3830, 0.5747},
+	{12.5 * 60, 0.8618, 0.7222, 4.72 * 60, 1.1919, 0.6527},
+	{18.5 * 60, 0.7562, 0.7825, 6.99 * 60, 1.0458, 0.7223},
+	{27.0 * 60, 0.6200, 0.8126, 10.21 * 60, 0.9220, 0.7582},
+	{38.3 * 60, 0.5043, 0.8434, 14.48 * 60, 0.8205, 0.7957},
+	{54.3 * 60, 0.4410, 0.8693, 20.53 * 60, 0.7305, 0.8279},
+	{77.0 * 60, 0.4000, 0.8910, 29.11 * 60, 0.6502, 0.8553},
+	{109.0 * 60, 0.3750, 0.9092, 41.20 * 60, 0.5950, 0.8757},
+	{146.0 * 60, 0.3500, 0.9222, 55.19 * 60, 0.5545, 0.8903},
+	{187.0 * 60, 0.3295, 0.9319, 70.69 * 60, 0.5333, 0.8997},
+	{239.0 * 60, 0.3065, 0.9403, 90.34 * 60, 0.5189, 0.9073},
+	{305.0 * 60, 0.2835, 0.9477, 115.29 * 60, 0.5181, 0.9122},
+	{390.0 * 60, 0.2610, 0.9544, 147.42 * 60, 0.5176, 0.9171},
+	{498.0 * 60, 0.2480, 0.9602, 188.24 * 60, 0.5172, 0.9217},
+	{635.0 * 60, 0.2327, 0.9653, 240.03 * 60, 0.5119, 0.9267},
+}
+
+// Compartments returns the tissue compartments described by the parameter
+// set. Nitrogen and helium tissues are paired by their number; a parameter
+// set without tissues, or with only one gas, is completed from ZHL16C.
+func (b *Buehlmann) Compartments() []Compartment {
+	if b == nil || len(b.Tissues) == 0 {
+		return slices.Clone(ZHL16C)
+	}
+
+	byNumber := map[int]*Compartment{}
+	var numbers []int
+	for _, t := range b.Tissues {
+		c, ok := byNumber[t.Number]
+		if !ok {
+			c = &Compartment{}
+			if t.Number >= 1 && t.Number <= len(ZHL16C) {
+				*c = ZHL16C[t.Number-1]
+			}
+			byNumber[t.Number] = c
+			numbers = append(numbers, t.Number)
+		}
+
+		switch t.Gas {
+		case "n2":
+			c.N2HalfLife, c.N2A, c.N2B = t.HalfLife, t.A, t.B
+		case "he":
+			c.HeHalfLife, c.HeA, c.HeB = t.HalfLife, t.A, t.B
+		}
+	}
+
+	slices.Sort(numbers)
+	compartments := make([]Compartment, 0, len(numbers))
+	for _, n := range numbers {
+		c := byNumber[n]
+		if c.N2HalfLife > 0 && c.HeHalfLife > 0 {
+			compartments = append(compartments, *c)
+		}
+	}
+	return compartments
+}
+
+// GradientFactors returns GF low and GF high of the parameter set, each
+// defaulting to 1.0 (plain Bühlmann) when not given.
+func (b *Buehlmann) GradientFactors() (low, high float64) {
+	low, high = 1, 1
+	if b == nil {
+		return low, high
+	}
+	if b.GradientFactorLow != nil {
+		low = *b.GradientFactorLow
+	}
+	if b.GradientFactorHigh != nil {
+		high = *b.GradientFactorHigh
+	}
+	return low, high
+}
+
+// TissueState holds the inert gas loading of the Bühlmann tissue compartments.
+// Pressures are given in bar.
+type TissueState struct {
+	Compartments []Compartment
+	N2           []float64
+	He           []float64
+}
+
+// NewTissueState returns tissues saturated with air at the given surface
+// pressure in bar. A nil parameter set uses ZHL16C.
+func NewTissueState(params *Buehlmann, surfacePressure float64) *TissueState {
+	compartments := params.Compartments()
+	s := &TissueState{
+		Compartments: compartments,
+		N2:           make([]float64, len(compartments)),
+		He:           make([]float64, len(compartments)),
+	}
+	for i := range compartments {
+		s.N2[i] = (surfacePressure - waterVapourPressure) * airN2Fraction
+	}
+	return s
+}
+
+// Clone returns a deep copy of the tissue state.
+func (s *TissueState) Clone() *TissueState {
+	return &TissueState{
+		Compartments: s.Compartments,
+		N2:           slices.Clone(s.N2),
+		He:           slices.Clone(s.He),
+	}
+}
+
+// Expose loads the tissues for duration seconds while the ambient pressure
+// changes linearly from startPressure to endPressure (both in bar) breathing
+// a gas with the given inert fractions, using the Schreiner equation.
+func (s *TissueState) Expose(startPressure, endPressure, duration float64, gas GasFractions) {
+	if duration <= 0 {
+		return
+	}
+	rate := (endPressure - startPressure) / duration
+	alveolar := math.Max(startPressure-waterVapourPressure, 0)
+
+	for i, c := range s.Compartments {
+		s.N2[i] = schreiner(s.N2[i], alveolar*gas.N2, rate*gas.N2, c.N2HalfLife, duration)
+		s.He[i] = schreiner(s.He[i], alveolar*gas.He, rate*gas.He, c.HeHalfLife, duration)
+	}
+}
+
+func schreiner(initial, inspired, rate, halfLife, duration float64) float64 {
+	k := math.Ln2 / halfLife
+	return inspired + rate*(duration-1/k) - (inspired-initial-rate/k)*math.Exp(-k*duration)
+}
+
+// Ceiling returns the lowest ambient pressure in bar the tissues tolerate at
+// the given gradient factor.
+func (s *TissueState) Ceiling(gf float64) float64 {
+	ceiling := 0.0
+	for i, c := range s.Compartments {
+		p := s.N2[i] + s.He[i]
+		if p <= 0 {
+			continue
+		}
+		a := (c.N2A*s.N2[i] + c.HeA*s.He[i]) / p
+		b := (c.N2B*s.N2[i] + c.HeB*s.He[i]) / p
+		tolerated := (p - a*gf) / (gf/b + 1 - gf)
+		ceiling = math.Max(ceiling, tolerated)
+	}
+	return ceiling
+}
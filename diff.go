@@ -0,0 +1,409 @@
+package uddf
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// diffTolerance is the absolute difference below which Diff considers two
+// floats equal.
+const diffTolerance = 1e-9
+
+// ChangeKind tells whether an object was added, removed or changed.
+type ChangeKind string
+
+const (
+	Added   ChangeKind = "added"
+	Removed ChangeKind = "removed"
+	Changed ChangeKind = "changed"
+)
+
+// Change describes how a dive, site, mix or buddy differs between two
+// documents.
+type Change struct {
+	Kind   ChangeKind
+	Type   string // "dive", "site", "mix" or "buddy"
+	ID     string
+	Path   string // XML path of the object, e.g. /uddf/divesite/site[@id='s1']
+	Fields []FieldChange
+}
+
+// FieldChange is a single changed value. Old and New hold the value in the
+// respective document and are nil where it is missing.
+type FieldChange struct {
+	Path string // XML path of the value
+	Old  any
+	New  any
+}
+
+func (c FieldChange) String() string {
+	return fmt.Sprintf("%s: %s -> %s", c.Path, formatDiffValue(c.Old), formatDiffValue(c.New))
+}
+
+func formatDiffValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "<none>"
+	case Time:
+		return v.String()
+	case string:
+		return fmt.Sprintf("%q", v)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// Clone returns a deep copy of the document.
+func (u *UDDF) Clone() *UDDF {
+	if u == nil {
+		return nil
+	}
+	return copyValue(reflect.ValueOf(u)).Interface().(*UDDF)
+}
+
+// copyValue returns a deep copy of v. Structs with unexported fields, such
+// as Time, are copied by value.
+func copyValue(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		c := reflect.New(v.Type().Elem())
+		c.Elem().Set(copyValue(v.Elem()))
+		return c
+	case reflect.Slice:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := range v.Len() {
+			c.Index(i).Set(copyValue(v.Index(i)))
+		}
+		return c
+	case reflect.Map:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			c.SetMapIndex(iter.Key(), copyValue(iter.Value()))
+		}
+		return c
+	case reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		for i := range v.NumField() {
+			if !v.Type().Field(i).IsExported() {
+				return v
+			}
+			c.Field(i).Set(copyValue(v.Field(i)))
+		}
+		return c
+	default:
+		return v
+	}
+}
+
+// Equal reports whether both documents hold the same data. Floats are
+// considered equal if they differ by at most tolerance.
+func (u *UDDF) Equal(other *UDDF, tolerance float64) bool {
+	if u == nil || other == nil {
+		return u == other
+	}
+	d := differ{tolerance: tolerance}
+	d.value("/uddf", reflect.ValueOf(*u), reflect.ValueOf(*other))
+	return len(d.changes) == 0
+}
+
+// Diff reports the dives, sites, mixes and buddies that were added to,
+// removed from or changed between a and b, matched by their ID. Changes
+// carry the XML path of the object in b, or in a for removed objects.
+func Diff(a, b *UDDF) []Change {
+	var changes []Change
+	for _, typ := range []string{"dive", "site", "mix", "buddy"} {
+		before, after := diffObjects(a, typ), diffObjects(b, typ)
+		for _, old := range before {
+			if _, ok := findDiffObject(after, old.id); !ok {
+				changes = append(changes, Change{Kind: Removed, Type: typ, ID: old.id, Path: old.path})
+			}
+		}
+		for _, obj := range after {
+			old, ok := findDiffObject(before, obj.id)
+			if !ok {
+				changes = append(changes, Change{Kind: Added, Type: typ, ID: obj.id, Path: obj.path})
+				continue
+			}
+
+			d := differ{tolerance: diffTolerance}
+			d.value(obj.path, old.value, obj.value)
+			if len(d.changes) > 0 {
+				changes = append(changes, Change{Kind: Changed, Type: typ, ID: obj.id, Path: obj.path, Fields: d.changes})
+			}
+		}
+	}
+	return changes
+}
+
+type diffObject struct {
+	id    string
+	path  string
+	value reflect.Value
+}
+
+func findDiffObject(objects []diffObject, id string) (diffObject, bool) {
+	for _, o := range objects {
+		if o.id == id {
+			return o, true
+		}
+	}
+	return diffObject{}, false
+}
+
+// diffObjects lists the objects of the given type in u.
+func diffObjects(u *UDDF, typ string) []diffObject {
+	if u == nil {
+		return nil
+	}
+
+	var objects []diffObject
+	add := func(path, id string, v any) {
+		objects = append(objects, diffObject{id: id, path: fmt.Sprintf("%s[@id='%s']", path, id), value: reflect.ValueOf(v)})
+	}
+
+	switch typ {
+	case "dive":
+		for i, group := range u.ProfileData.RepetitionGroup {
+			path := fmt.Sprintf("/uddf/profiledata/repetitiongroup[%d]", i+1)
+			if group.ID != nil {
+				path = fmt.Sprintf("/uddf/profiledata/repetitiongroup[@id='%s']", *group.ID)
+			}
+			for _, dive := range group.Dives {
+				add(path+"/dive", dive.ID, dive)
+			}
+		}
+	case "site":
+		if u.DiveSite != nil {
+			for _, site := range u.DiveSite.Sites {
+				add("/uddf/divesite/site", site.ID, site)
+			}
+		}
+	case "mix":
+		if u.GasDefinitions != nil {
+			for _, mix := range u.GasDefinitions.Mixes {
+				add("/uddf/gasdefinitions/mix", mix.ID, mix)
+			}
+		}
+	case "buddy":
+		for _, buddy := range u.Diver.Buddies {
+			add("/uddf/diver/buddy", buddy.Id, buddy)
+		}
+	}
+	return objects
+}
+
+var (
+	timeType          = reflect.TypeOf(Time{})
+	flexibleFloatType = reflect.TypeOf(FlexibleFloat{})
+)
+
+// differ collects the field level differences of two values of the same
+// type.
+type differ struct {
+	tolerance float64
+	changes   []FieldChange
+}
+
+func (d *differ) report(path string, a, b reflect.Value) {
+	change := FieldChange{Path: path}
+	if a.IsValid() {
+		change.Old = a.Interface()
+	}
+	if b.IsValid() {
+		change.New = b.Interface()
+	}
+	d.changes = append(d.changes, change)
+}
+
+// value compares a and b, either of which may be invalid if it is missing.
+func (d *differ) value(path string, a, b reflect.Value) {
+	if !a.IsValid() || !b.IsValid() {
+		if a.IsValid() || b.IsValid() {
+			d.report(path, a, b)
+		}
+		return
+	}
+
+	switch a.Type() {
+	case timeType:
+		ta, tb := a.Interface().(Time), b.Interface().(Time)
+		if !time.Time(ta).Equal(time.Time(tb)) || ta.Precision() != tb.Precision() {
+			d.report(path, a, b)
+		}
+		return
+	case flexibleFloatType:
+		d.value(path, a.Field(0), b.Field(0))
+		return
+	}
+
+	switch a.Kind() {
+	case reflect.Pointer:
+		d.value(path, indirect(a), indirect(b))
+	case reflect.Float32, reflect.Float64:
+		if math.Abs(a.Float()-b.Float()) > d.tolerance {
+			d.report(path, a, b)
+		}
+	case reflect.Struct:
+		d.fields(path, a, b)
+	case reflect.Slice:
+		d.slice(path, a, b)
+	default:
+		if !reflect.DeepEqual(a.Interface(), b.Interface()) {
+			d.report(path, a, b)
+		}
+	}
+}
+
+func (d *differ) fields(path string, a, b reflect.Value) {
+	for i := range a.NumField() {
+		field := a.Type().Field(i)
+		if !field.IsExported() || field.Name == "XMLName" {
+			continue
+		}
+		if field.Anonymous {
+			d.value(path, a.Field(i), b.Field(i))
+			continue
+		}
+
+		name, ok := xmlFieldName(field)
+		if !ok {
+			continue
+		}
+		fieldPath := path
+		if name != "" {
+			fieldPath += "/" + name
+		}
+		d.value(fieldPath, a.Field(i), b.Field(i))
+	}
+}
+
+// slice compares elements by ID if every element has one, by position
+// otherwise.
+func (d *differ) slice(path string, a, b reflect.Value) {
+	if ids, ok := sliceIDs(a); ok {
+		if idsB, ok := sliceIDs(b); ok {
+			for i, id := range ids {
+				elemPath := fmt.Sprintf("%s[@id='%s']", path, id)
+				if j := indexOf(idsB, id); j >= 0 {
+					d.value(elemPath, a.Index(i), b.Index(j))
+				} else {
+					d.value(elemPath, a.Index(i), reflect.Value{})
+				}
+			}
+			for j, id := range idsB {
+				if indexOf(ids, id) < 0 {
+					d.value(fmt.Sprintf("%s[@id='%s']", path, id), reflect.Value{}, b.Index(j))
+				}
+			}
+			return
+		}
+	}
+
+	for i := range max(a.Len(), b.Len()) {
+		var x, y reflect.Value
+		if i < a.Len() {
+			x = a.Index(i)
+		}
+		if i < b.Len() {
+			y = b.Index(i)
+		}
+		d.value(fmt.Sprintf("%s[%d]", path, i+1), x, y)
+	}
+}
+
+func indirect(v reflect.Value) reflect.Value {
+	if v.IsNil() {
+		return reflect.Value{}
+	}
+	return v.Elem()
+}
+
+func indexOf(ids []string, id string) int {
+	for i, s := range ids {
+		if s == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// sliceIDs returns the IDs of the elements of v, or false if any element
+// has none.
+func sliceIDs(v reflect.Value) ([]string, bool) {
+	if v.Len() == 0 || v.Type().Elem().Kind() != reflect.Struct {
+		return nil, false
+	}
+
+	ids := make([]string, v.Len())
+	for i := range v.Len() {
+		id := objectID(v.Index(i))
+		if id == "" {
+			return nil, false
+		}
+		ids[i] = id
+	}
+	return ids, true
+}
+
+// objectID returns the value of the ID or Id field of a struct, including
+// promoted fields.
+func objectID(v reflect.Value) string {
+	for _, name := range []string{"ID", "Id"} {
+		f := v.FieldByName(name)
+		if !f.IsValid() {
+			continue
+		}
+		if f.Kind() == reflect.Pointer {
+			if f.IsNil() {
+				return ""
+			}
+			f = f.Elem()
+		}
+		if f.Kind() == reflect.String {
+			return f.String()
+		}
+	}
+	return ""
+}
+
+// xmlFieldName returns the path segment of a struct field from its xml tag:
+// "@name" for attributes and "" for character data. It returns false for
+// fields that are not part of the XML representation.
+func xmlFieldName(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("xml")
+	if tag == "-" {
+		return "", false
+	}
+
+	name, opts, _ := strings.Cut(tag, ",")
+	for _, opt := range strings.Split(opts, ",") {
+		switch opt {
+		case "attr":
+			if name == "" {
+				name = field.Name
+			}
+			return "@" + name, true
+		case "chardata", "innerxml", "cdata":
+			return "", true
+		case "comment", "any":
+			return "", false
+		}
+	}
+	if name == "" {
+		name = field.Name
+	}
+	return strings.ReplaceAll(name, ">", "/"), true
+}
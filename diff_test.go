@@ -0,0 +1,74 @@
+package uddf
+
+import "testing"
+
+func TestCloneEqualDiff(t *testing.T) {
+	original, err := ParseFile("testdata/valid.uddf")
+	if err != nil {
+		t.Fatalf("failed to parse file: %v", err)
+	}
+
+	t.Run("clone should be equal and independent", func(t *testing.T) {
+		clone := original.Clone()
+		if !original.Equal(clone, 0) {
+			t.Fatal("expected clone to equal original")
+		}
+
+		clone.GasDefinitions.Mixes[1].Name = "EAN32"
+		*clone.GasDefinitions.Mixes[1].O2 = 0.33
+		if original.GasDefinitions.Mixes[1].Name != "Nitrox 32" || *original.GasDefinitions.Mixes[1].O2 != 0.32 {
+			t.Error("expected changes to the clone to leave the original untouched")
+		}
+		if original.Equal(clone, 0) {
+			t.Error("expected modified clone to differ")
+		}
+	})
+
+	t.Run("equal should apply float tolerance", func(t *testing.T) {
+		clone := original.Clone()
+		clone.ProfileData.RepetitionGroup[0].Dives[0].InformationAfterDive.GreatestDepth += 0.001
+		if original.Equal(clone, 0) {
+			t.Error("expected documents to differ without tolerance")
+		}
+		if !original.Equal(clone, 0.01) {
+			t.Error("expected documents to be equal within tolerance")
+		}
+	})
+
+	t.Run("diff should report changes by ID", func(t *testing.T) {
+		changed := original.Clone()
+		changed.ProfileData.RepetitionGroup[0].Dives[0].InformationAfterDive.GreatestDepth = 31
+		changed.GasDefinitions.Mixes = changed.GasDefinitions.Mixes[1:]
+		changed.Diver.Buddies = append(changed.Diver.Buddies, Buddy{BuddyOwnerShared: BuddyOwnerShared{Id: "buddy1"}})
+
+		changes := Diff(original, changed)
+		if len(changes) != 3 {
+			t.Fatalf("expected 3 changes, got %+v", changes)
+		}
+
+		dive := changes[0]
+		if dive.Kind != Changed || dive.Type != "dive" || dive.ID != "dive1" {
+			t.Errorf("expected changed dive1, got %+v", dive)
+		}
+		want := "/uddf/profiledata/repetitiongroup[@id='rg1']/dive[@id='dive1']/informationafterdive/greatestdepth"
+		if len(dive.Fields) != 1 || dive.Fields[0].Path != want {
+			t.Fatalf("expected one field change at %s, got %+v", want, dive.Fields)
+		}
+		if dive.Fields[0].Old != 30.5 || dive.Fields[0].New != 31.0 {
+			t.Errorf("expected 30.5 -> 31, got %s", dive.Fields[0])
+		}
+
+		if mix := changes[1]; mix.Kind != Removed || mix.ID != "air" || mix.Path != "/uddf/gasdefinitions/mix[@id='air']" {
+			t.Errorf("expected removed mix air, got %+v", mix)
+		}
+		if buddy := changes[2]; buddy.Kind != Added || buddy.Type != "buddy" || buddy.ID != "buddy1" {
+			t.Errorf("expected added buddy1, got %+v", buddy)
+		}
+	})
+
+	t.Run("identical documents should have no diff", func(t *testing.T) {
+		if changes := Diff(original, original.Clone()); len(changes) != 0 {
+			t.Errorf("expected no changes, got %+v", changes)
+		}
+	})
+}
@@ -0,0 +1,133 @@
+package uddf
+
+import "math"
+
+// Water densities in kg/m^3.
+const (
+	FreshWater = 1000.0
+	SaltWater  = 1030.0
+)
+
+// Environment is the surface pressure and the water density of a dive. All
+// calculations of the package convert between depths and ambient pressures
+// through it. The zero value is sea level in salt water. Free gas volumes
+// are always given at sea level pressure, wherever the dive takes place.
+type Environment struct {
+	SurfacePressure float64 // bar [computed from Altitude]
+	Altitude        float64 // metres above sea level, used if SurfacePressure is not set
+	Density         float64 // water density in kg/m^3 [SaltWater]
+}
+
+// Environment returns the environment of d. The surface pressure is taken
+// from InformationBeforeDive.SurfacePressure, or computed from the altitude
+// of the dive or else of its site. The density is that of the site.
+func (u *UDDF) Environment(d *Dive) Environment {
+	var e Environment
+	info := d.InformationBeforeDive
+	if info.SurfacePressure != nil {
+		e.SurfacePressure = *info.SurfacePressure / pascalPerBar
+	}
+	site := u.diveSite(d)
+	switch {
+	case info.Altitude != nil:
+		e.Altitude = *info.Altitude
+	case site != nil && site.Geography != nil && site.Geography.Altitude != nil:
+		e.Altitude = *site.Geography.Altitude
+	}
+	if site != nil && site.SideData != nil && site.SideData.Density != nil {
+		e.Density = *site.SideData.Density
+	}
+	return e
+}
+
+// Surface returns the surface pressure in bar.
+func (e Environment) Surface() float64 {
+	switch {
+	case e.SurfacePressure > 0:
+		return e.SurfacePressure
+	case e.Altitude != 0:
+		// international standard atmosphere
+		return seaLevelPressure * math.Pow(1-2.25577e-5*e.Altitude, 5.25588)
+	}
+	return seaLevelPressure
+}
+
+func (e Environment) density() float64 {
+	if e.Density > 0 {
+		return e.Density
+	}
+	return SaltWater
+}
+
+// Pressure returns the ambient pressure in bar at depth metres.
+func (e Environment) Pressure(depth float64) float64 {
+	return e.Surface() + depth*e.density()*gravity/pascalPerBar
+}
+
+// Depth returns the depth in metres at which the ambient pressure is
+// pressure bar, the inverse of Pressure.
+func (e Environment) Depth(pressure float64) float64 {
+	return (pressure - e.Surface()) * pascalPerBar / (e.density() * gravity)
+}
+
+// MOD returns the maximum operation depth in metres at which the oxygen
+// partial pressure of m reaches maxPo2 bar.
+func (e Environment) MOD(m *Mix, maxPo2 float64) float64 {
+	o2 := m.Fractions().O2
+	if o2 <= 0 {
+		return math.Inf(1)
+	}
+	return e.Depth(maxPo2 / o2)
+}
+
+// END returns the equivalent narcotic depth in metres of m at depth,
+// counting oxygen as narcotic.
+func (e Environment) END(m *Mix, depth float64) float64 {
+	return math.Max(e.Depth(e.Pressure(depth)*m.Fractions().narcotic()), 0)
+}
+
+// EAD returns the equivalent air depth in metres of m at depth, i.e. the
+// depth at which air has the same nitrogen partial pressure.
+func (e Environment) EAD(m *Mix, depth float64) float64 {
+	return math.Max(e.Depth(e.Pressure(depth)*m.Fractions().N2/Air.N2), 0)
+}
+
+// GasDensity returns the density of m in kg/m^3 (g/l) at depth, assuming an
+// ideal gas at 20 °C.
+func (e Environment) GasDensity(m *Mix, depth float64) float64 {
+	f := m.Fractions()
+	molarMass := f.O2*molarMassO2 + f.He*molarMassHe + f.N2*molarMassN2 + f.Ar*molarMassAr + f.H2*molarMassH2
+	return e.Pressure(depth) * pascalPerBar * molarMass / (gasConstant * standardTemperature)
+}
+
+// BestMix returns the gas for a dive to depth metres with the highest oxygen
+// fraction not exceeding maxPo2 bar, and just enough helium to keep the
+// equivalent narcotic depth at or above maxEND metres.
+func (e Environment) BestMix(depth, maxPo2, maxEND float64) GasFractions {
+	pressure := e.Pressure(depth)
+	o2 := math.Min(maxPo2/pressure, 1)
+	narcotic := math.Min(e.Pressure(maxEND)/pressure, 1)
+	he := math.Max(1-narcotic, 0)
+	if o2+he > 1 {
+		he = 1 - o2
+	}
+	return GasFractions{O2: o2, He: he, N2: math.Max(1-o2-he, 0)}
+}
+
+// Complete fills in the derived fields of m like Mix.Complete, with the
+// depths in e.
+func (e Environment) Complete(m *Mix, maxPo2 float64) {
+	if m.N2 == nil {
+		m.N2 = ptr(m.Fractions().N2)
+	}
+	if m.MaximumPo2 == nil {
+		m.MaximumPo2 = ptr(maxPo2 * pascalPerBar)
+	}
+	mod := e.MOD(m, *m.MaximumPo2/pascalPerBar)
+	if m.MaximumOperationDepth == nil && !math.IsInf(mod, 1) {
+		m.MaximumOperationDepth = ptr(mod)
+	}
+	if m.EquivalentAirDepth == nil && m.MaximumOperationDepth != nil {
+		m.EquivalentAirDepth = ptr(e.EAD(m, *m.MaximumOperationDepth))
+	}
+}
@@ -0,0 +1,96 @@
+package uddf
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestEnvironment(t *testing.T) {
+	t.Run("zero value should be sea level in salt water", func(t *testing.T) {
+		var e Environment
+		if p := e.Pressure(10); math.Abs(p-2.0233) > 0.001 {
+			t.Errorf("expected 2.023 bar at 10 m, got %v", p)
+		}
+		if d := e.Depth(e.Pressure(33)); math.Abs(d-33) > 1e-9 {
+			t.Errorf("expected 33 m, got %v", d)
+		}
+	})
+
+	t.Run("altitude and fresh water should change pressures", func(t *testing.T) {
+		lake := Environment{Altitude: 2000, Density: FreshWater}
+		if s := lake.Surface(); math.Abs(s-0.795) > 0.001 {
+			t.Errorf("expected 0.795 bar at 2000 m, got %v", s)
+		}
+		if p := lake.Pressure(10) - lake.Surface(); math.Abs(p-0.98067) > 1e-5 {
+			t.Errorf("expected 0.981 bar per 10 m of fresh water, got %v", p)
+		}
+		if explicit := (Environment{SurfacePressure: 0.9, Altitude: 2000}); explicit.Surface() != 0.9 {
+			t.Errorf("expected the explicit surface pressure, got %v", explicit.Surface())
+		}
+
+		nitrox := GasFractions{O2: 0.32, N2: 0.68}.Mix("ean32")
+		if fresh := (Environment{Density: FreshWater}).MOD(&nitrox, 1.4); fresh <= nitrox.MOD(1.4) {
+			t.Errorf("expected a deeper MOD in fresh water, got %v", fresh)
+		}
+		if high := lake.MOD(&nitrox, 1.4); high <= nitrox.MOD(1.4) {
+			t.Errorf("expected a deeper MOD at altitude, got %v", high)
+		}
+	})
+
+	t.Run("dives should take their environment from the document", func(t *testing.T) {
+		u := buildDocument(t, func(b *Builder) {
+			b.AddMix("Air", 0.21, 0).AddSite("Lake", 46.5, 8.0).
+				AddDive(func(d *DiveBuilder) {
+					d.At(time.Date(2024, 8, 1, 9, 0, 0, 0, time.UTC)).Site("Lake")
+				})
+		})
+		site := &u.DiveSite.Sites[0]
+		site.Geography.Altitude = ptr(1500.0)
+		site.SideData = &SiteData{Density: ptr(FreshWater)}
+		d := &u.ProfileData.RepetitionGroup[0].Dives[0]
+
+		if e := u.Environment(d); e != (Environment{Altitude: 1500, Density: FreshWater}) {
+			t.Errorf("unexpected environment %+v", e)
+		}
+		d.InformationBeforeDive.SurfacePressure = ptr(85000.0)
+		if e := u.Environment(d); e.Surface() != 0.85 {
+			t.Errorf("expected the recorded surface pressure, got %v", e.Surface())
+		}
+	})
+
+	t.Run("decompression should be longer at altitude", func(t *testing.T) {
+		params := Buehlmann{GradientFactorLow: ptr(0.3), GradientFactorHigh: ptr(0.85)}
+		air := Air.Mix("air")
+		sea, err := NoDecoLimit(30, air, params, PlanOptions{})
+		if err != nil {
+			t.Fatalf("failed to compute no-deco limit: %v", err)
+		}
+		high, err := NoDecoLimit(30, air, params, PlanOptions{Environment: Environment{Altitude: 3000, Density: FreshWater}})
+		if err != nil {
+			t.Fatalf("failed to compute no-deco limit: %v", err)
+		}
+		if high >= sea {
+			t.Errorf("expected a shorter no-deco limit at altitude, got %v and %v", high, sea)
+		}
+	})
+
+	t.Run("mixes should be completed for the dives breathing them", func(t *testing.T) {
+		u := buildDocument(t, func(b *Builder) {
+			b.AddMix("Nitrox 32", 0.32, 0).AddMix("Air", 0.21, 0).AddSite("Lake", 46.5, 8.0).
+				AddDive(func(d *DiveBuilder) {
+					d.At(time.Date(2024, 8, 1, 9, 0, 0, 0, time.UTC)).Site("Lake").Tank("Nitrox 32", 0.012, 200e5, 60e5)
+				})
+		})
+		u.DiveSite.Sites[0].SideData = &SiteData{Density: ptr(FreshWater)}
+		u.CompleteMixes(1.4)
+
+		nitrox, air := u.GasDefinitions.Mixes[0], u.GasDefinitions.Mixes[1]
+		if want := (Environment{Density: FreshWater}).MOD(&nitrox, 1.4); *nitrox.MaximumOperationDepth != want {
+			t.Errorf("expected the fresh water MOD of %.1f m, got %v", want, *nitrox.MaximumOperationDepth)
+		}
+		if want := air.MOD(1.4); *air.MaximumOperationDepth != want {
+			t.Errorf("expected the sea level MOD of %.1f m for an unused mix, got %v", want, *air.MaximumOperationDepth)
+		}
+	})
+}
@@ -0,0 +1,172 @@
+package uddf
+
+import (
+	"fmt"
+	"time"
+)
+
+// ServiceOptions configure EquipmentUsage. Zero values select the defaults.
+type ServiceOptions struct {
+	Now          time.Time     // the date service states are computed for [time.Now()]
+	DiveInterval int           // dives between two services, zero if service is by date only
+	RemindBefore time.Duration // time before a due date an item is due soon [30 days]
+	RemindDives  int           // dives before the dive interval an item is due soon [5]
+}
+
+func (o ServiceOptions) withDefaults() ServiceOptions {
+	if o.Now.IsZero() {
+		o.Now = time.Now()
+	}
+	if o.RemindBefore == 0 {
+		o.RemindBefore = 30 * 24 * time.Hour
+	}
+	if o.RemindDives == 0 {
+		o.RemindDives = 5
+	}
+	return o
+}
+
+// ServiceStatus tells whether a piece of equipment needs service.
+type ServiceStatus int
+
+const (
+	// ServiceUnknown is the status of items without a service schedule.
+	ServiceUnknown ServiceStatus = iota
+	ServiceOK
+	ServiceDueSoon
+	ServiceOverdue
+)
+
+var serviceStatusNames = []string{"unknown", "ok", "due soon", "overdue"}
+
+func (s ServiceStatus) String() string {
+	if s < 0 || int(s) >= len(serviceStatusNames) {
+		return fmt.Sprintf("ServiceStatus(%d)", int(s))
+	}
+	return serviceStatusNames[s]
+}
+
+// EquipmentUsage is the use and service state of a piece of equipment.
+type EquipmentUsage struct {
+	Kind     EquipmentKind
+	Part     *EquipmentPart
+	Dives    int       // dives linking the item
+	DiveTime float64   // total duration of these dives in seconds
+	LastUsed time.Time // start of the last of these dives, zero if unused
+
+	// DueDate is when the next service is due: NextServiceDate, or
+	// ServiceInterval days after the purchase if no service date is known.
+	// It is zero if neither is given.
+	DueDate time.Time
+	// DivesSinceService counts the dives after the last service, which is
+	// taken to be ServiceInterval days before NextServiceDate. Without
+	// both, all dives are counted.
+	DivesSinceService int
+	// DivesUntilService is the number of dives left until the dive interval
+	// is reached, negative if exceeded. It is zero without a dive interval.
+	DivesUntilService int
+	Status            ServiceStatus
+}
+
+// EquipmentUsage lists every piece of the owner's equipment, including its
+// equipment configuration, with the dives it was used on and its service
+// state. An item counts as used on a dive if the dive links to it from
+// EquipmentUsed or from its tank data. ServiceInterval is given in days.
+func (u *UDDF) EquipmentUsage(opts ServiceOptions) []EquipmentUsage {
+	opts = opts.withDefaults()
+
+	var usage []EquipmentUsage
+	byID := map[string]int{}
+	for _, item := range u.Inventory() {
+		if _, dup := byID[item.Part.Id]; dup && item.Part.Id != "" {
+			continue
+		}
+		byID[item.Part.Id] = len(usage)
+		usage = append(usage, EquipmentUsage{Kind: item.Kind, Part: item.Part})
+	}
+	delete(byID, "")
+
+	// starts holds the start of every dive an item was used on
+	starts := make([][]time.Time, len(usage))
+	for _, d := range u.dives() {
+		start := u.DiveTime(d, nil)
+		var links []Link
+		if eq := d.InformationAfterDive.EquipmentUsed; eq != nil {
+			links = append(links, eq.Links...)
+		}
+		for _, t := range d.TankData {
+			links = append(links, t.Links...)
+		}
+
+		counted := map[int]bool{}
+		for _, l := range links {
+			i, ok := byID[l.Ref]
+			if !ok || counted[i] {
+				continue
+			}
+			counted[i] = true
+			e := &usage[i]
+			e.Dives++
+			e.DiveTime += d.InformationAfterDive.DiveDuration
+			if start.After(e.LastUsed) {
+				e.LastUsed = start
+			}
+			starts[i] = append(starts[i], start)
+		}
+	}
+
+	for i := range usage {
+		e := &usage[i]
+		var interval time.Duration
+		if e.Part.ServiceInterval != nil && *e.Part.ServiceInterval > 0 {
+			interval = time.Duration(*e.Part.ServiceInterval) * 24 * time.Hour
+		}
+
+		var lastService time.Time
+		switch {
+		case e.Part.NextServiceDate != nil:
+			e.DueDate = e.Part.NextServiceDate.DateTime.In(nil)
+			if interval > 0 {
+				lastService = e.DueDate.Add(-interval)
+			}
+		case interval > 0 && e.Part.Purchase != nil && e.Part.Purchase.DateTime != nil:
+			lastService = e.Part.Purchase.DateTime.In(nil)
+			e.DueDate = lastService.Add(interval)
+		}
+
+		for _, start := range starts[i] {
+			if !start.Before(lastService) {
+				e.DivesSinceService++
+			}
+		}
+
+		if e.DueDate.IsZero() && opts.DiveInterval == 0 {
+			continue
+		}
+		e.Status = ServiceOK
+		if opts.DiveInterval > 0 {
+			e.DivesUntilService = opts.DiveInterval - e.DivesSinceService
+			if e.DivesUntilService <= opts.RemindDives {
+				e.Status = ServiceDueSoon
+			}
+		}
+		if !e.DueDate.IsZero() && !opts.Now.Before(e.DueDate.Add(-opts.RemindBefore)) {
+			e.Status = ServiceDueSoon
+		}
+		if (!e.DueDate.IsZero() && !opts.Now.Before(e.DueDate)) || (opts.DiveInterval > 0 && e.DivesUntilService <= 0) {
+			e.Status = ServiceOverdue
+		}
+	}
+	return usage
+}
+
+// ServiceReminders returns the equipment that is due soon or overdue.
+func (u *UDDF) ServiceReminders(opts ServiceOptions) []EquipmentUsage {
+	var due []EquipmentUsage
+	for _, e := range u.EquipmentUsage(opts) {
+		if e.Status == ServiceDueSoon || e.Status == ServiceOverdue {
+			due = append(due, e)
+		}
+	}
+	return due
+}
@@ -0,0 +1,105 @@
+package uddf
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestEquipmentUsage(t *testing.T) {
+	start := time.Date(2024, 1, 10, 9, 0, 0, 0, time.UTC)
+	u := buildDocument(t, func(b *Builder) {
+		b.AddMix("Air", 0.21, 0)
+		for i := range 4 {
+			b.AddDive(func(d *DiveBuilder) {
+				d.At(start.AddDate(0, i, 0)).Duration(time.Hour).GreatestDepth(20)
+				d.Dive().InformationAfterDive.EquipmentUsed = &EquipmentUsed{Links: []Link{{Ref: "reg-1"}, {Ref: "bcd-1"}, {Ref: "reg-1"}}}
+				if i%2 == 0 {
+					d.Dive().TankData = []TankData{{ID: fmt.Sprintf("td-%d", i), Links: []Link{{Ref: "tank-12l"}, {Ref: "mix-air"}}}}
+				}
+			})
+		}
+	})
+
+	date := func(y int, m time.Month, d int) *Date {
+		return &Date{DateTime: Time(time.Date(y, m, d, 0, 0, 0, 0, time.UTC))}
+	}
+	tank := Tank{}
+	tank.Id, tank.Name = "tank-12l", "12 l steel"
+	tank.Purchase = &Purchase{DateTime: ptr(Time(time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)))}
+	tank.ServiceInterval = ptr(365)
+	u.Diver.Owner.Equipment = &Equipment{}
+	u.Diver.Owner.Equipment.Regulators = []EquipmentPart{{Id: "reg-1", Name: "Primary", NextServiceDate: date(2024, 7, 1), ServiceInterval: ptr(365)}}
+	u.Diver.Owner.Equipment.Tanks = []Tank{tank}
+	u.Diver.Owner.Equipment.Fins = []EquipmentPart{{Id: "fins-1", Name: "Fins"}}
+	u.Diver.Owner.Equipment.EquipmentConfiguration.BuoyancyControlDevices = []EquipmentPart{{Id: "bcd-1", Name: "Wing", NextServiceDate: date(2024, 3, 1)}}
+
+	usage := u.EquipmentUsage(ServiceOptions{Now: time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC)})
+	byID := map[string]EquipmentUsage{}
+	for _, e := range usage {
+		byID[e.Part.Id] = e
+	}
+	if len(usage) != 4 {
+		t.Fatalf("expected 4 items, got %d", len(usage))
+	}
+
+	t.Run("should count dives and dive time", func(t *testing.T) {
+		reg := byID["reg-1"]
+		if reg.Kind != "regulator" || reg.Dives != 4 || reg.DiveTime != 4*3600 {
+			t.Errorf("unexpected regulator usage %+v", reg)
+		}
+		if !reg.LastUsed.Equal(start.AddDate(0, 3, 0)) {
+			t.Errorf("expected last use in April, got %v", reg.LastUsed)
+		}
+		if tank := byID["tank-12l"]; tank.Kind != "tank" || tank.Dives != 2 {
+			t.Errorf("expected the tank to be used on 2 dives, got %+v", tank)
+		}
+		if fins := byID["fins-1"]; fins.Dives != 0 || fins.Status != ServiceUnknown {
+			t.Errorf("expected unused fins without schedule, got %+v", fins)
+		}
+	})
+
+	t.Run("should compute the service state by date", func(t *testing.T) {
+		if reg := byID["reg-1"]; reg.Status != ServiceDueSoon || reg.DivesSinceService != 4 {
+			t.Errorf("expected the regulator to be due soon, got %s with %d dives", reg.Status, reg.DivesSinceService)
+		}
+		if tank := byID["tank-12l"]; !tank.DueDate.Equal(time.Date(2024, 5, 31, 0, 0, 0, 0, time.UTC)) || tank.Status != ServiceOverdue {
+			t.Errorf("expected the tank to be overdue since its purchase, got %s due %v", tank.Status, tank.DueDate)
+		}
+		if bcd := byID["bcd-1"]; bcd.Kind != "buoyancycontroldevice" || bcd.Status != ServiceOverdue {
+			t.Errorf("expected the configured wing to be overdue, got %+v", bcd)
+		}
+	})
+
+	t.Run("service states should have names", func(t *testing.T) {
+		if s := ServiceOverdue.String(); s != "overdue" {
+			t.Errorf("expected 'overdue', got %q", s)
+		}
+		if s := ServiceStatus(7).String(); s != "ServiceStatus(7)" {
+			t.Errorf("expected 'ServiceStatus(7)', got %q", s)
+		}
+	})
+
+	t.Run("should compute the service state by dives", func(t *testing.T) {
+		opts := ServiceOptions{Now: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), DiveInterval: 10, RemindDives: 2}
+		due := u.ServiceReminders(opts)
+		if len(due) != 0 {
+			t.Errorf("expected no reminders, got %+v", due)
+		}
+
+		opts.DiveInterval = 4
+		due = u.ServiceReminders(opts)
+		if len(due) != 3 {
+			t.Fatalf("expected 3 reminders, got %+v", due)
+		}
+		for _, e := range due {
+			want := ServiceOverdue
+			if e.Part.Id == "tank-12l" {
+				want = ServiceDueSoon
+			}
+			if e.Status != want {
+				t.Errorf("%s: expected %s, got %s (%d dives left)", e.Part.Id, want, e.Status, e.DivesUntilService)
+			}
+		}
+	})
+}
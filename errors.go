@@ -0,0 +1,107 @@
+package uddf
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseError describes a failure to decode a UDDF document. It is returned
+// for XML syntax errors as well as for values that cannot be converted into
+// their Go representation (e.g. a malformed date).
+type ParseError struct {
+	Line     int    // 1-based line of the offending input, 0 if unknown
+	Column   int    // 1-based column of the offending input, 0 if unknown
+	Path     string // slash-separated element path, e.g. /uddf/profiledata/repetitiongroup/dive
+	RawValue string // the raw text that failed to convert, empty for syntax errors
+	Err      error
+}
+
+func (e *ParseError) Error() string {
+	var b strings.Builder
+	b.WriteString("failed to decode UDDF file")
+	if e.Line > 0 {
+		fmt.Fprintf(&b, " at line %d, column %d", e.Line, e.Column)
+	}
+	if e.Path != "" {
+		fmt.Fprintf(&b, " (%s)", e.Path)
+	}
+	if e.RawValue != "" {
+		fmt.Fprintf(&b, ": invalid value %q", e.RawValue)
+	}
+	if e.Err != nil {
+		fmt.Fprintf(&b, ": %v", e.Err)
+	}
+	return b.String()
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// newParseError converts an error returned by the XML decoder into a
+// *ParseError, filling in position and path information from the decoder
+// state and the original input.
+func newParseError(data []byte, decoder *xml.Decoder, err error) *ParseError {
+	var pe *ParseError
+	if !errors.As(err, &pe) {
+		pe = &ParseError{Err: err}
+		var numErr *strconv.NumError
+		if errors.As(err, &numErr) {
+			pe.RawValue = numErr.Num
+		}
+	}
+
+	offset := decoder.InputOffset()
+	if pe.Line == 0 {
+		pe.Line, pe.Column = decoder.InputPos()
+	}
+
+	// Attribute errors only know their own name, so prefix it with the
+	// path of the element carrying it.
+	base := elementPath(data, offset)
+	switch {
+	case pe.Path == "":
+		pe.Path = base
+	case strings.HasPrefix(pe.Path, "@"):
+		pe.Path = base + "/" + pe.Path
+	}
+
+	return pe
+}
+
+// elementPath re-scans data and returns the path of the innermost element
+// that is open at the given byte offset. An element whose end tag finishes
+// exactly at offset is still reported, since the decoder consumes the end
+// tag before converting the element's value.
+func elementPath(data []byte, offset int64) string {
+	decoder := newDecoder(data)
+	decoder.Strict = false
+
+	var stack []string
+	for decoder.InputOffset() < offset {
+		tok, err := decoder.RawToken()
+		if err != nil {
+			break
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			stack = append(stack, t.Name.Local)
+		case xml.EndElement:
+			if decoder.InputOffset() >= offset {
+				return "/" + strings.Join(stack, "/")
+			}
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		}
+	}
+
+	if len(stack) == 0 {
+		return ""
+	}
+	return "/" + strings.Join(stack, "/")
+}
@@ -0,0 +1,332 @@
+package uddf
+
+import (
+	"fmt"
+	"math"
+)
+
+// GasFractions are the component fractions of a breathing gas.
+type GasFractions struct {
+	O2 float64
+	He float64
+	N2 float64
+	Ar float64
+	H2 float64
+}
+
+// Air is the composition of atmospheric air as used by the calculations.
+var Air = GasFractions{O2: 0.21, N2: 0.79}
+
+// Fractions returns the gas composition of the mix. A mix without any
+// fractions is treated as air; a missing nitrogen fraction is the remainder
+// of the other components.
+func (m *Mix) Fractions() GasFractions {
+	if m == nil || (m.O2 == nil && m.He == nil && m.N2 == nil && m.Ar == nil && m.H2 == nil) {
+		return Air
+	}
+
+	f := GasFractions{O2: deref(m.O2), He: deref(m.He), Ar: deref(m.Ar), H2: deref(m.H2)}
+	if m.N2 != nil {
+		f.N2 = *m.N2
+	} else {
+		f.N2 = math.Max(1-f.O2-f.He-f.Ar-f.H2, 0)
+	}
+	return f
+}
+
+const (
+	gasConstant         = 8.314462618 // J/(mol·K)
+	standardTemperature = 293.15      // K, 20 °C
+)
+
+// Molar masses in kg/mol.
+const (
+	molarMassO2 = 0.031998
+	molarMassHe = 0.0040026
+	molarMassN2 = 0.028014
+	molarMassAr = 0.039948
+	molarMassH2 = 0.002016
+)
+
+// Name returns the conventional name of the gas, e.g. "Air", "Nitrox 32" or
+// "Trimix 18/45".
+func (f GasFractions) Name() string {
+	o2, he := math.Round(f.O2*100), math.Round(f.He*100)
+	switch {
+	case o2 == 100:
+		return "Oxygen"
+	case he == 0 && o2 == 21:
+		return "Air"
+	case he == 0:
+		return fmt.Sprintf("Nitrox %.0f", o2)
+	case o2+he == 100:
+		return fmt.Sprintf("Heliox %.0f/%.0f", o2, he)
+	default:
+		return fmt.Sprintf("Trimix %.0f/%.0f", o2, he)
+	}
+}
+
+// Mix returns a mix with the given ID, these fractions and their
+// conventional name.
+func (f GasFractions) Mix(id string) Mix {
+	m := Mix{ID: id, Name: f.Name(), O2: ptr(f.O2), N2: ptr(f.N2)}
+	if f.He > 0 {
+		m.He = ptr(f.He)
+	}
+	if f.Ar > 0 {
+		m.Ar = ptr(f.Ar)
+	}
+	if f.H2 > 0 {
+		m.H2 = ptr(f.H2)
+	}
+	return m
+}
+
+// narcotic returns the fraction of the gas considered narcotic. Oxygen is
+// counted as narcotic, like nitrogen and argon.
+func (f GasFractions) narcotic() float64 {
+	return f.O2 + f.N2 + f.Ar
+}
+
+// MOD returns the maximum operation depth in metres at which the oxygen
+// partial pressure of the mix reaches maxPo2 bar, at sea level in salt
+// water. See Environment.MOD for other environments.
+func (m *Mix) MOD(maxPo2 float64) float64 {
+	return Environment{}.MOD(m, maxPo2)
+}
+
+// END returns the equivalent narcotic depth in metres of the mix at depth,
+// counting oxygen as narcotic, at sea level in salt water.
+func (m *Mix) END(depth float64) float64 {
+	return Environment{}.END(m, depth)
+}
+
+// EAD returns the equivalent air depth in metres of the mix at depth, i.e.
+// the depth at which air has the same nitrogen partial pressure, at sea
+// level in salt water.
+func (m *Mix) EAD(depth float64) float64 {
+	return Environment{}.EAD(m, depth)
+}
+
+// Density returns the density of the mix in kg/m^3 (g/l) at depth, assuming
+// an ideal gas at 20 °C, at sea level in salt water.
+func (m *Mix) Density(depth float64) float64 {
+	return Environment{}.GasDensity(m, depth)
+}
+
+// Complete fills in the derived fields of the mix that are not set: the
+// nitrogen fraction, MaximumPo2 (set to maxPo2 bar, stored in Pa),
+// MaximumOperationDepth at MaximumPo2 and the EquivalentAirDepth at that
+// depth, at sea level in salt water. See Environment.Complete for other
+// environments.
+func (m *Mix) Complete(maxPo2 float64) {
+	Environment{}.Complete(m, maxPo2)
+}
+
+// Complete fills in the derived fields of every mix, see Mix.Complete.
+func (g *GasDefinitions) Complete(maxPo2 float64) {
+	for i := range g.Mixes {
+		g.Mixes[i].Complete(maxPo2)
+	}
+}
+
+// CompleteMixes fills in the derived fields of every mix of the document
+// like GasDefinitions.Complete, in the environment of the dives breathing
+// it. If these differ, the one giving the shallowest maximum operation depth
+// is used; mixes no dive breathes are completed for sea level.
+func (u *UDDF) CompleteMixes(maxPo2 float64) {
+	if u.GasDefinitions == nil {
+		return
+	}
+	envs := map[string][]Environment{}
+	for _, d := range u.dives() {
+		env := u.Environment(d)
+		for _, t := range d.TankData {
+			for _, l := range t.Links {
+				envs[l.Ref] = append(envs[l.Ref], env)
+			}
+		}
+		if d.Samples != nil {
+			for _, w := range d.Samples.Waypoints {
+				if w.SwitchMix != nil {
+					envs[w.SwitchMix.Ref] = append(envs[w.SwitchMix.Ref], env)
+				}
+			}
+		}
+	}
+
+	for i := range u.GasDefinitions.Mixes {
+		m := &u.GasDefinitions.Mixes[i]
+		limit := maxPo2
+		if m.MaximumPo2 != nil {
+			limit = *m.MaximumPo2 / pascalPerBar
+		}
+		var env Environment
+		for j, e := range envs[m.ID] {
+			if j == 0 || e.MOD(m, limit) < env.MOD(m, limit) {
+				env = e
+			}
+		}
+		env.Complete(m, maxPo2)
+	}
+}
+
+// BestMix returns the gas for a dive to depth metres with the highest oxygen
+// fraction not exceeding maxPo2 bar, and just enough helium to keep the
+// equivalent narcotic depth at or above maxEND metres, at sea level in salt
+// water.
+func BestMix(depth, maxPo2, maxEND float64) GasFractions {
+	return Environment{}.BestMix(depth, maxPo2, maxEND)
+}
+
+// BlendStep is one step of a partial pressure blend.
+type BlendStep struct {
+	Gas      string  // "drain", "he", "o2" or "air"
+	Added    float64 // pressure added in Pa, negative when draining
+	Pressure float64 // tank pressure in Pa after the step
+}
+
+// BlendPlan describes how to fill a tank with a target mix.
+type BlendPlan struct {
+	Steps  []BlendStep
+	Result GasFractions
+}
+
+// PartialPressureBlend plans how to reach targetPressure (Pa) of target in a
+// tank holding startPressure of start: drain if necessary, add helium, add
+// oxygen and top off with air. Gases are treated as ideal.
+func PartialPressureBlend(start GasFractions, startPressure float64, target GasFractions, targetPressure float64) (*BlendPlan, error) {
+	if targetPressure <= 0 || startPressure < 0 {
+		return nil, fmt.Errorf("invalid pressures %.0f Pa to %.0f Pa", startPressure, targetPressure)
+	}
+
+	additions := func(p float64) (he, o2, air float64) {
+		he = targetPressure*target.He - p*start.He
+		o2 = (targetPressure*target.O2 - p*start.O2 - Air.O2*(targetPressure-p-he)) / (1 - Air.O2)
+		air = targetPressure - p - he - o2
+		return he, o2, air
+	}
+	feasible := func(p float64) bool {
+		he, o2, air := additions(p)
+		const tolerance = -1 // Pa
+		return he >= tolerance && o2 >= tolerance && air >= tolerance
+	}
+
+	// Draining keeps the composition, so search for the highest remaining
+	// pressure from which the target can still be reached.
+	pressure := math.Min(startPressure, targetPressure)
+	if !feasible(pressure) {
+		if !feasible(0) {
+			return nil, fmt.Errorf("%s cannot be blended from helium, oxygen and air", target.Name())
+		}
+		low, high := 0.0, pressure
+		for high-low > 1 {
+			mid := (low + high) / 2
+			if feasible(mid) {
+				low = mid
+			} else {
+				high = mid
+			}
+		}
+		pressure = low
+	}
+
+	plan := &BlendPlan{}
+	if pressure < startPressure {
+		plan.Steps = append(plan.Steps, BlendStep{Gas: "drain", Added: pressure - startPressure, Pressure: pressure})
+	}
+
+	he, o2, air := additions(pressure)
+	for _, step := range []BlendStep{{Gas: "he", Added: he}, {Gas: "o2", Added: o2}, {Gas: "air", Added: air}} {
+		if step.Added <= 0 {
+			continue
+		}
+		pressure += step.Added
+		step.Pressure = pressure
+		plan.Steps = append(plan.Steps, step)
+	}
+
+	plan.Result = plan.result(start, startPressure)
+	return plan, nil
+}
+
+// result returns the composition the plan produces from the start gas.
+func (p *BlendPlan) result(start GasFractions, startPressure float64) GasFractions {
+	pressure := startPressure
+	var o2, he, n2 float64 = start.O2 * pressure, start.He * pressure, (1 - start.O2 - start.He) * pressure
+	for _, step := range p.Steps {
+		switch step.Gas {
+		case "drain":
+			ratio := step.Pressure / pressure
+			o2, he, n2 = o2*ratio, he*ratio, n2*ratio
+		case "he":
+			he += step.Added
+		case "o2":
+			o2 += step.Added
+		case "air":
+			o2 += step.Added * Air.O2
+			n2 += step.Added * Air.N2
+		}
+		pressure = step.Pressure
+	}
+	if pressure == 0 {
+		return GasFractions{}
+	}
+	return GasFractions{O2: o2 / pressure, He: he / pressure, N2: n2 / pressure}
+}
+
+// ContinuousBlend describes a fill where oxygen and helium are injected into
+// the intake of an air compressor.
+type ContinuousBlend struct {
+	DrainTo  float64 // tank pressure in Pa to drain to before filling
+	IntakeO2 float64 // oxygen fraction of the compressor intake
+	IntakeHe float64 // helium fraction of the compressor intake
+	Result   GasFractions
+}
+
+// ContinuousBlendPlan plans how to reach targetPressure (Pa) of target in a
+// tank holding startPressure of start with a compressor whose intake must
+// not exceed maxIntakeO2. Gases are treated as ideal.
+func ContinuousBlendPlan(start GasFractions, startPressure float64, target GasFractions, targetPressure, maxIntakeO2 float64) (*ContinuousBlend, error) {
+	if targetPressure <= 0 || startPressure < 0 {
+		return nil, fmt.Errorf("invalid pressures %.0f Pa to %.0f Pa", startPressure, targetPressure)
+	}
+
+	intake := func(p float64) (o2, he float64, ok bool) {
+		if p >= targetPressure {
+			return 0, 0, false
+		}
+		added := targetPressure - p
+		o2 = (targetPressure*target.O2 - p*start.O2) / added
+		he = (targetPressure*target.He - p*start.He) / added
+		// Whatever is not helium or added oxygen comes from air
+		ok = he >= 0 && o2 <= maxIntakeO2 && o2 >= Air.O2*(1-he)-1e-9 && o2+he <= 1+1e-9
+		return o2, he, ok
+	}
+
+	pressure := math.Min(startPressure, targetPressure)
+	if _, _, ok := intake(pressure); !ok {
+		if _, _, ok := intake(0); !ok {
+			return nil, fmt.Errorf("%s cannot be blended continuously with an intake of at most %.0f%% oxygen", target.Name(), maxIntakeO2*100)
+		}
+		low, high := 0.0, pressure
+		for high-low > 1 {
+			mid := (low + high) / 2
+			if _, _, ok := intake(mid); ok {
+				low = mid
+			} else {
+				high = mid
+			}
+		}
+		pressure = low
+	}
+
+	o2, he, _ := intake(pressure)
+	added := targetPressure - pressure
+	result := GasFractions{
+		O2: (pressure*start.O2 + added*o2) / targetPressure,
+		He: (pressure*start.He + added*he) / targetPressure,
+	}
+	result.N2 = 1 - result.O2 - result.He
+	return &ContinuousBlend{DrainTo: pressure, IntakeO2: o2, IntakeHe: he, Result: result}, nil
+}
@@ -0,0 +1,143 @@
+package uddf
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMixCalculations(t *testing.T) {
+	nitrox := Mix{ID: "ean32", Name: "Nitrox 32", O2: ptr(0.32)}
+
+	t.Run("should compute MOD, EAD, END and density", func(t *testing.T) {
+		if mod := nitrox.MOD(1.4); math.Abs(mod-33.3) > 0.1 {
+			t.Errorf("expected MOD of 33.3 m, got %.2f", mod)
+		}
+		if ead := nitrox.EAD(30); math.Abs(ead-24.4) > 0.1 {
+			t.Errorf("expected EAD of 24.4 m, got %.2f", ead)
+		}
+		if end := nitrox.END(30); math.Abs(end-30) > 0.01 {
+			t.Errorf("expected END of 30 m for a nitrox, got %.2f", end)
+		}
+
+		air := Mix{ID: "air", Name: "Air"}
+		if density := air.Density(0); math.Abs(density-1.2) > 0.01 {
+			t.Errorf("expected air density of 1.2 g/l at the surface, got %.3f", density)
+		}
+		trimix := Mix{ID: "tx", Name: "Trimix 18/45", O2: ptr(0.18), He: ptr(0.45)}
+		if trimix.Density(60) >= air.Density(60) {
+			t.Error("expected trimix to be less dense than air")
+		}
+	})
+
+	t.Run("should fill missing fields", func(t *testing.T) {
+		mix := nitrox
+		mix.Complete(1.4)
+
+		if mix.N2 == nil || math.Abs(*mix.N2-0.68) > 1e-9 {
+			t.Errorf("expected n2 fraction 0.68, got %v", mix.N2)
+		}
+		if mix.MaximumPo2 == nil || *mix.MaximumPo2 != 1.4e5 {
+			t.Errorf("expected maximum ppO2 1.4e5 Pa, got %v", mix.MaximumPo2)
+		}
+		if mix.MaximumOperationDepth == nil || math.Abs(*mix.MaximumOperationDepth-33.3) > 0.1 {
+			t.Errorf("expected MOD of 33.3 m, got %v", mix.MaximumOperationDepth)
+		}
+		if mix.EquivalentAirDepth == nil {
+			t.Error("expected equivalent air depth to be set")
+		}
+
+		parsed := nitrox
+		parsed.MaximumPo2 = ptr(1.6e5)
+		parsed.Complete(1.4)
+		if *parsed.MaximumPo2 != 1.6e5 || math.Abs(*parsed.MaximumOperationDepth-39.5) > 0.1 {
+			t.Errorf("expected the given maximum ppO2 of 1.6 bar to be used, got a MOD of %v", *parsed.MaximumOperationDepth)
+		}
+	})
+
+	t.Run("should select best mix for depth", func(t *testing.T) {
+		mix := BestMix(60, 1.4, 30)
+		if math.Abs(mix.O2-0.198) > 0.001 {
+			t.Errorf("expected 19.8%% oxygen, got %.3f", mix.O2)
+		}
+		if math.Abs(mix.He-0.428) > 0.001 {
+			t.Errorf("expected 42.8%% helium, got %.3f", mix.He)
+		}
+		if name := mix.Name(); name != "Trimix 20/43" {
+			t.Errorf("expected name 'Trimix 20/43', got '%s'", name)
+		}
+
+		shallow := BestMix(30, 1.4, 30)
+		if shallow.He != 0 || shallow.Name() != "Nitrox 35" {
+			t.Errorf("expected Nitrox 35 without helium at 30 m, got %+v", shallow)
+		}
+	})
+}
+
+func TestPartialPressureBlend(t *testing.T) {
+	trimix := GasFractions{O2: 0.21, He: 0.35, N2: 0.44}
+
+	t.Run("should blend trimix into an empty tank", func(t *testing.T) {
+		plan, err := PartialPressureBlend(GasFractions{}, 0, trimix, 200e5)
+		if err != nil {
+			t.Fatalf("failed to plan blend: %v", err)
+		}
+
+		if len(plan.Steps) != 3 || plan.Steps[0].Gas != "he" || plan.Steps[1].Gas != "o2" || plan.Steps[2].Gas != "air" {
+			t.Fatalf("expected helium, oxygen and air, got %+v", plan.Steps)
+		}
+		if math.Abs(plan.Steps[0].Added-70e5) > 1 {
+			t.Errorf("expected 70 bar helium, got %.1f bar", plan.Steps[0].Added/1e5)
+		}
+		if math.Abs(plan.Steps[1].Added-18.61e5) > 0.01e5 {
+			t.Errorf("expected 18.6 bar oxygen, got %.2f bar", plan.Steps[1].Added/1e5)
+		}
+		assertFractions(t, plan.Result, trimix)
+	})
+
+	t.Run("should drain when the start gas is too rich", func(t *testing.T) {
+		target := GasFractions{O2: 0.32, N2: 0.68}
+		plan, err := PartialPressureBlend(GasFractions{O2: 0.40, N2: 0.60}, 150e5, target, 200e5)
+		if err != nil {
+			t.Fatalf("failed to plan blend: %v", err)
+		}
+
+		if plan.Steps[0].Gas != "drain" {
+			t.Fatalf("expected drain first, got %+v", plan.Steps)
+		}
+		assertFractions(t, plan.Result, target)
+	})
+
+	t.Run("hypoxic nitrox should be impossible", func(t *testing.T) {
+		_, err := PartialPressureBlend(GasFractions{}, 0, GasFractions{O2: 0.18, N2: 0.82}, 200e5)
+		if err == nil {
+			t.Error("expected error for hypoxic nitrox, got nil")
+		}
+	})
+}
+
+func TestContinuousBlendPlan(t *testing.T) {
+	target := GasFractions{O2: 0.32, N2: 0.68}
+
+	plan, err := ContinuousBlendPlan(GasFractions{O2: 0.21, N2: 0.79}, 50e5, target, 200e5, 0.40)
+	if err != nil {
+		t.Fatalf("failed to plan blend: %v", err)
+	}
+	if plan.DrainTo != 50e5 {
+		t.Errorf("expected no drain, got %v", plan.DrainTo)
+	}
+	if math.Abs(plan.IntakeO2-0.3567) > 0.001 {
+		t.Errorf("expected intake of 35.7%% oxygen, got %.4f", plan.IntakeO2)
+	}
+	assertFractions(t, plan.Result, target)
+
+	if _, err := ContinuousBlendPlan(GasFractions{}, 0, GasFractions{O2: 0.5, N2: 0.5}, 200e5, 0.40); err == nil {
+		t.Error("expected error for intake above 40% oxygen, got nil")
+	}
+}
+
+func assertFractions(t *testing.T, actual, expected GasFractions) {
+	t.Helper()
+	if math.Abs(actual.O2-expected.O2) > 0.001 || math.Abs(actual.He-expected.He) > 0.001 {
+		t.Errorf("expected %s, got %+v", expected.Name(), actual)
+	}
+}
@@ -2,7 +2,11 @@ module github.com/Flipez/go-uddf
 
 go 1.24.5
 
-require github.com/go-playground/validator/v10 v10.27.0
+require (
+	github.com/go-playground/validator/v10 v10.27.0
+	github.com/klauspost/compress v1.18.0
+	golang.org/x/text v0.22.0
+)
 
 require (
 	github.com/gabriel-vasile/mimetype v1.4.8 // indirect
@@ -12,5 +16,4 @@ require (
 	golang.org/x/crypto v0.33.0 // indirect
 	golang.org/x/net v0.34.0 // indirect
 	golang.org/x/sys v0.30.0 // indirect
-	golang.org/x/text v0.22.0 // indirect
 )
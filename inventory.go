@@ -0,0 +1,178 @@
+package uddf
+
+import "strings"
+
+// EquipmentKind is the type of a piece of equipment, named after its
+// element. Parts of cameras and rebreathers are prefixed with the element
+// they belong to.
+type EquipmentKind string
+
+const (
+	KindBoots                 EquipmentKind = "boots"
+	KindBuoyancyControlDevice EquipmentKind = "buoyancycontroldevice"
+	KindCameraBody            EquipmentKind = "camera/body"
+	KindCameraFlash           EquipmentKind = "camera/flash"
+	KindCameraHousing         EquipmentKind = "camera/housing"
+	KindCameraLens            EquipmentKind = "camera/lens"
+	KindCompass               EquipmentKind = "compass"
+	KindCompressor            EquipmentKind = "compressor"
+	KindDiveComputer          EquipmentKind = "divecomputer"
+	KindFins                  EquipmentKind = "fins"
+	KindGloves                EquipmentKind = "gloves"
+	KindKnife                 EquipmentKind = "knife"
+	KindLead                  EquipmentKind = "lead"
+	KindLight                 EquipmentKind = "light"
+	KindMask                  EquipmentKind = "mask"
+	KindRebreather            EquipmentKind = "rebreather"
+	KindO2Sensor              EquipmentKind = "rebreather/o2sensor"
+	KindRegulator             EquipmentKind = "regulator"
+	KindScooter               EquipmentKind = "scooter"
+	KindSuit                  EquipmentKind = "suit"
+	KindTank                  EquipmentKind = "tank"
+	KindVariousPieces         EquipmentKind = "variouspieces"
+	KindVideoCamera           EquipmentKind = "videocamera"
+	KindWatch                 EquipmentKind = "watch"
+)
+
+// InventoryItem is a piece of equipment in an Inventory.
+type InventoryItem struct {
+	Kind EquipmentKind
+	// Part holds the data common to all equipment. It points into the
+	// document, as does Value.
+	Part *EquipmentPart
+	// Value is the element itself: a *Tank, *Suit, *Rebreather or *Lead
+	// for these kinds, Part otherwise.
+	Value any
+	// Configuration is set for items of an EquipmentConfiguration.
+	Configuration bool
+}
+
+// Inventory is a flat list of equipment.
+type Inventory []InventoryItem
+
+// Inventory returns the equipment of the owner.
+func (u *UDDF) Inventory() Inventory {
+	if u.Diver.Owner.Equipment == nil {
+		return nil
+	}
+	return u.Diver.Owner.Equipment.Inventory()
+}
+
+// Inventory returns the equipment followed by the items of its
+// configuration.
+func (e *Equipment) Inventory() Inventory {
+	inv := e.EquipmentContent.Inventory()
+	for i := range e.Compressors {
+		inv = append(inv, InventoryItem{Kind: KindCompressor, Part: &e.Compressors[i], Value: &e.Compressors[i]})
+	}
+	for _, item := range e.EquipmentConfiguration.EquipmentContent.Inventory() {
+		item.Configuration = true
+		inv = append(inv, item)
+	}
+	return inv
+}
+
+// Inventory returns every piece of equipment in c.
+func (c *EquipmentContent) Inventory() Inventory {
+	var inv Inventory
+	parts := func(kind EquipmentKind, ps []EquipmentPart) {
+		for i := range ps {
+			inv = append(inv, InventoryItem{Kind: kind, Part: &ps[i], Value: &ps[i]})
+		}
+	}
+	part := func(kind EquipmentKind, p *EquipmentPart) {
+		if p != nil {
+			inv = append(inv, InventoryItem{Kind: kind, Part: p, Value: p})
+		}
+	}
+
+	parts(KindBoots, c.Boots)
+	parts(KindBuoyancyControlDevice, c.BuoyancyControlDevices)
+	for i := range c.Cameras {
+		camera := &c.Cameras[i]
+		part(KindCameraBody, camera.Body)
+		parts(KindCameraFlash, camera.Flashes)
+		part(KindCameraHousing, camera.Housing)
+		part(KindCameraLens, camera.Lens)
+	}
+	parts(KindCompass, c.Compasses)
+	parts(KindDiveComputer, c.DiveComputers)
+	parts(KindFins, c.Fins)
+	parts(KindGloves, c.Gloves)
+	parts(KindKnife, c.Knives)
+	for i := range c.Leads {
+		inv = append(inv, InventoryItem{Kind: KindLead, Part: &c.Leads[i].EquipmentPart, Value: &c.Leads[i]})
+	}
+	parts(KindLight, c.Lights)
+	parts(KindMask, c.Masks)
+	for i := range c.Rebreathers {
+		r := &c.Rebreathers[i]
+		inv = append(inv, InventoryItem{Kind: KindRebreather, Part: &r.EquipmentPart, Value: r})
+		parts(KindO2Sensor, r.O2Sensors)
+	}
+	parts(KindRegulator, c.Regulators)
+	parts(KindScooter, c.Scooters)
+	for i := range c.Suits {
+		inv = append(inv, InventoryItem{Kind: KindSuit, Part: &c.Suits[i].EquipmentPart, Value: &c.Suits[i]})
+	}
+	for i := range c.Tanks {
+		inv = append(inv, InventoryItem{Kind: KindTank, Part: &c.Tanks[i].EquipmentPart, Value: &c.Tanks[i]})
+	}
+	parts(KindVariousPieces, c.VariousPieces)
+	parts(KindVideoCamera, c.VideoCameras)
+	parts(KindWatch, c.Watches)
+	return inv
+}
+
+// Find returns the item with the given ID, nil if there is none.
+func (inv Inventory) Find(id string) *InventoryItem {
+	for i := range inv {
+		if inv[i].Part.Id == id {
+			return &inv[i]
+		}
+	}
+	return nil
+}
+
+// Filter returns the items for which keep returns true.
+func (inv Inventory) Filter(keep func(item *InventoryItem) bool) Inventory {
+	var result Inventory
+	for i := range inv {
+		if keep(&inv[i]) {
+			result = append(result, inv[i])
+		}
+	}
+	return result
+}
+
+// OfKind returns the items of the given kinds.
+func (inv Inventory) OfKind(kinds ...EquipmentKind) Inventory {
+	return inv.Filter(func(item *InventoryItem) bool {
+		for _, k := range kinds {
+			if item.Kind == k {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// ByManufacturer returns the items whose manufacturer's name contains name,
+// ignoring case.
+func (inv Inventory) ByManufacturer(name string) Inventory {
+	name = strings.ToLower(name)
+	return inv.Filter(func(item *InventoryItem) bool {
+		m := item.Part.Manufacturer
+		return m != nil && strings.Contains(strings.ToLower(m.Name), name)
+	})
+}
+
+// BySerialNumber returns the items with the given serial number, ignoring
+// case and surrounding space.
+func (inv Inventory) BySerialNumber(serial string) Inventory {
+	serial = strings.TrimSpace(serial)
+	return inv.Filter(func(item *InventoryItem) bool {
+		s := item.Part.SerialNumber
+		return s != nil && strings.EqualFold(strings.TrimSpace(*s), serial)
+	})
+}
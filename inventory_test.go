@@ -0,0 +1,123 @@
+package uddf
+
+import (
+	"strings"
+	"testing"
+)
+
+const inventoryDocument = `<uddf version="3.2.3">
+<diver>
+<owner id="owner">
+<personal><firstname>Jane</firstname><lastname>Diver</lastname></personal>
+<equipment>
+<camera>
+<body id="cam-body"><name>Body</name><manufacturer><name>Sony</name></manufacturer><serialnumber>SN-100</serialnumber></body>
+<flash id="flash-1"><name>Left strobe</name><manufacturer><name>Sea&amp;Sea</name></manufacturer></flash>
+<flash id="flash-2"><name>Right strobe</name><manufacturer><name>Sea&amp;Sea</name></manufacturer></flash>
+<housing id="cam-housing"><name>Housing</name><manufacturer><name>Nauticam</name></manufacturer></housing>
+</camera>
+<regulator id="reg-1"><name>Primary</name><manufacturer><name>Apeks</name></manufacturer><serialnumber>sn-200</serialnumber></regulator>
+<tank id="tank-1"><name>12 l</name><tankvolume>0.012</tankvolume></tank>
+<equipmentconfiguration>
+<name>Photo</name>
+<lead id="lead-1"><name>Weights</name><leadquantity>4</leadquantity></lead>
+</equipmentconfiguration>
+</equipment>
+</owner>
+</diver>
+</uddf>`
+
+func TestInventory(t *testing.T) {
+	u, err := Parse([]byte(inventoryDocument))
+	if err != nil {
+		t.Fatalf("failed to parse document: %v", err)
+	}
+	inv := u.Inventory()
+
+	t.Run("should list every item with its kind", func(t *testing.T) {
+		var got []string
+		for _, item := range inv {
+			got = append(got, string(item.Kind)+" "+item.Part.Id)
+		}
+		want := []string{
+			"camera/body cam-body", "camera/flash flash-1", "camera/flash flash-2", "camera/housing cam-housing",
+			"regulator reg-1", "tank tank-1", "lead lead-1",
+		}
+		if len(got) != len(want) {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("expected %v, got %v", want, got)
+				break
+			}
+		}
+	})
+
+	t.Run("should find items by ID", func(t *testing.T) {
+		tank := inv.Find("tank-1")
+		if tank == nil || tank.Value.(*Tank).TankVolume == nil || *tank.Value.(*Tank).TankVolume != 0.012 {
+			t.Errorf("unexpected tank %+v", tank)
+		}
+		lead := inv.Find("lead-1")
+		if lead == nil || !lead.Configuration || lead.Part != &lead.Value.(*Lead).EquipmentPart {
+			t.Errorf("unexpected lead %+v", lead)
+		}
+		if inv.Find("missing") != nil {
+			t.Error("expected no item for an unknown ID")
+		}
+	})
+
+	t.Run("should point into the document", func(t *testing.T) {
+		inv.Find("reg-1").Part.Name = "Octopus"
+		if u.Diver.Owner.Equipment.Regulators[0].Name != "Octopus" {
+			t.Error("expected the regulator to be renamed")
+		}
+		u.Diver.Owner.Equipment.Regulators[0].Name = "Primary"
+	})
+
+	t.Run("should filter by kind, manufacturer and serial number", func(t *testing.T) {
+		if flashes := inv.OfKind(KindCameraFlash); len(flashes) != 2 {
+			t.Errorf("expected 2 flashes, got %d", len(flashes))
+		}
+		if sea := inv.ByManufacturer("sea&SEA"); len(sea) != 2 || sea[0].Part.Id != "flash-1" {
+			t.Errorf("unexpected items %+v", sea)
+		}
+		if reg := inv.BySerialNumber(" SN-200 "); len(reg) != 1 || reg[0].Kind != KindRegulator {
+			t.Errorf("unexpected items %+v", reg)
+		}
+	})
+
+	t.Run("cameras without a body should list only their parts", func(t *testing.T) {
+		housing, err := Parse([]byte(`<uddf version="3.2.3"><diver><owner id="owner"><equipment><camera><housing id="housing-2"><name>Housing</name></housing></camera></equipment></owner></diver></uddf>`))
+		if err != nil {
+			t.Fatalf("failed to parse document: %v", err)
+		}
+		if inv := housing.Inventory(); len(inv) != 1 || inv[0].Kind != KindCameraHousing {
+			t.Errorf("expected only the housing, got %+v", inv)
+		}
+
+		data, err := Marshal(housing)
+		if err != nil {
+			t.Fatalf("failed to write document: %v", err)
+		}
+		if strings.Contains(string(data), "<body") {
+			t.Errorf("expected no body to be written, got %s", data)
+		}
+	})
+
+	t.Run("should write cameras back", func(t *testing.T) {
+		data, err := Marshal(u)
+		if err != nil {
+			t.Fatalf("failed to write document: %v", err)
+		}
+		again, err := Parse(data)
+		if err != nil {
+			t.Fatalf("failed to parse written document: %v", err)
+		}
+		cameras := again.Diver.Owner.Equipment.Cameras
+		if len(cameras) != 1 || cameras[0].Body == nil || cameras[0].Body.Id != "cam-body" || len(cameras[0].Flashes) != 2 || cameras[0].Housing == nil || cameras[0].Lens != nil {
+			t.Errorf("unexpected cameras %+v", cameras)
+		}
+	})
+}
@@ -0,0 +1,191 @@
+package uddf
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// Merge combines documents into a copy of the first one. The mixes, sites,
+// buddies and dives of the other documents are added unless an identical
+// object with the same ID already exists. IDs that are already taken by
+// different content are renamed and references to them updated. All other
+// data is taken from the first document only.
+func Merge(docs ...*UDDF) (*UDDF, error) {
+	if len(docs) == 0 || docs[0] == nil {
+		return nil, errors.New("no document to merge into")
+	}
+
+	result := docs[0].Clone()
+	for _, doc := range docs[1:] {
+		if doc != nil {
+			mergeInto(result, doc.Clone())
+		}
+	}
+	return result, nil
+}
+
+// mergeInto moves the mixes, sites, buddies and dives of src into dst.
+func mergeInto(dst, src *UDDF) {
+	dstIDs := documentIDs(dst)
+	taken := documentIDs(dst)
+	for id := range documentIDs(src) {
+		taken[id] = true
+	}
+
+	// Dives refer to the other objects, so these are deduplicated and
+	// renamed first. Duplicates keep their IDs, which still refer to the
+	// existing objects.
+	profileData := &src.ProfileData
+	duplicates := mergeDuplicates(dst, src, false)
+	renameIDs(src, dstIDs, taken, func(n *Node) bool {
+		return duplicates[n.Value] || n.Value == profileData
+	})
+	for ptr := range mergeDuplicates(dst, src, true) {
+		duplicates[ptr] = true
+	}
+	renameIDs(src, dstIDs, taken, func(n *Node) bool {
+		return duplicates[n.Value] || (n.Parent != nil && n.Parent.Parent == nil && n.Value != profileData)
+	})
+
+	if src.GasDefinitions != nil {
+		for i := range src.GasDefinitions.Mixes {
+			if mix := &src.GasDefinitions.Mixes[i]; !duplicates[mix] {
+				if dst.GasDefinitions == nil {
+					dst.GasDefinitions = &GasDefinitions{}
+				}
+				dst.GasDefinitions.Mixes = append(dst.GasDefinitions.Mixes, *mix)
+			}
+		}
+	}
+	if src.DiveSite != nil {
+		for i := range src.DiveSite.Sites {
+			if site := &src.DiveSite.Sites[i]; !duplicates[site] {
+				if dst.DiveSite == nil {
+					dst.DiveSite = &DiveSite{}
+				}
+				dst.DiveSite.Sites = append(dst.DiveSite.Sites, *site)
+			}
+		}
+	}
+	for i := range src.Diver.Buddies {
+		if buddy := &src.Diver.Buddies[i]; !duplicates[buddy] {
+			dst.Diver.Buddies = append(dst.Diver.Buddies, *buddy)
+		}
+	}
+	for _, group := range src.ProfileData.RepetitionGroup {
+		var dives []Dive
+		for i := range group.Dives {
+			if !duplicates[&group.Dives[i]] {
+				dives = append(dives, group.Dives[i])
+			}
+		}
+		if len(dives) > 0 {
+			group.Dives = dives
+			dst.ProfileData.RepetitionGroup = append(dst.ProfileData.RepetitionGroup, group)
+		}
+	}
+}
+
+// mergeDuplicates returns the dives, or the mixes, sites and buddies, of src
+// that equal the object with the same ID in dst.
+func mergeDuplicates(dst, src *UDDF, dives bool) map[any]bool {
+	existing := map[string]any{}
+	for _, obj := range mergeObjects(dst) {
+		existing[obj.id] = obj.ptr
+	}
+
+	duplicates := map[any]bool{}
+	for _, obj := range mergeObjects(src) {
+		if _, isDive := obj.ptr.(*Dive); isDive != dives {
+			continue
+		}
+		if old, ok := existing[obj.id]; ok && sameValue(old, obj.ptr) {
+			duplicates[obj.ptr] = true
+		}
+	}
+	return duplicates
+}
+
+// renameIDs gives every ID of src that is used in dst a new one not in
+// taken, and updates the references to it. Nodes for which skip returns true
+// are left alone together with their children.
+func renameIDs(src *UDDF, dstIDs, taken map[string]bool, skip func(n *Node) bool) {
+	renamed := map[string]string{}
+	Walk(src, Visitor{Node: func(n *Node) WalkAction {
+		if skip(n) {
+			return SkipChildren
+		}
+		if id, ok := n.Value.(*string); ok && n.Name == "@id" && dstIDs[*id] {
+			newID := *id
+			for i := 2; taken[newID]; i++ {
+				newID = fmt.Sprintf("%s-%d", *id, i)
+			}
+			taken[newID] = true
+			renamed[*id] = newID
+			*id = newID
+		}
+		return Continue
+	}})
+
+	Walk(src, Visitor{Node: func(n *Node) WalkAction {
+		if ref, ok := n.Value.(*string); ok && referenceAttrs[n.Name] {
+			if newID, ok := renamed[*ref]; ok {
+				*ref = newID
+			}
+		}
+		return Continue
+	}})
+}
+
+type mergeObject struct {
+	id  string
+	ptr any
+}
+
+// mergeObjects returns pointers to the mixes, sites, buddies and dives of u.
+func mergeObjects(u *UDDF) []mergeObject {
+	var objects []mergeObject
+	if u.GasDefinitions != nil {
+		for i := range u.GasDefinitions.Mixes {
+			objects = append(objects, mergeObject{u.GasDefinitions.Mixes[i].ID, &u.GasDefinitions.Mixes[i]})
+		}
+	}
+	if u.DiveSite != nil {
+		for i := range u.DiveSite.Sites {
+			objects = append(objects, mergeObject{u.DiveSite.Sites[i].ID, &u.DiveSite.Sites[i]})
+		}
+	}
+	for i := range u.Diver.Buddies {
+		objects = append(objects, mergeObject{u.Diver.Buddies[i].Id, &u.Diver.Buddies[i]})
+	}
+	for _, group := range u.ProfileData.RepetitionGroup {
+		for i := range group.Dives {
+			objects = append(objects, mergeObject{group.Dives[i].ID, &group.Dives[i]})
+		}
+	}
+	return objects
+}
+
+// documentIDs returns every ID used in u.
+func documentIDs(u *UDDF) map[string]bool {
+	ids := map[string]bool{}
+	Walk(u, Visitor{Node: func(n *Node) WalkAction {
+		if id, ok := n.Value.(*string); ok && n.Name == "@id" && *id != "" {
+			ids[*id] = true
+		}
+		return Continue
+	}})
+	return ids
+}
+
+// sameValue reports whether two pointers refer to equal values.
+func sameValue(a, b any) bool {
+	va, vb := reflect.ValueOf(a).Elem(), reflect.ValueOf(b).Elem()
+	if va.Type() != vb.Type() {
+		return false
+	}
+	d := differ{tolerance: diffTolerance}
+	d.value("", va, vb)
+	return len(d.changes) == 0
+}
@@ -0,0 +1,62 @@
+package uddf
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMerge(t *testing.T) {
+	start := time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC)
+	build := func(t *testing.T, o2 float64, dives ...time.Duration) *UDDF {
+		return buildDocument(t, func(b *Builder) {
+			b.AddMix("Bottom", o2, 0).AddSite("Reef", 1, 2)
+			for _, offset := range dives {
+				b.AddDive(func(d *DiveBuilder) {
+					d.At(start.Add(offset)).Site("Reef").Tank("Bottom", 0.012, 200e5, 60e5)
+					d.Sample(0, 0).Sample(30*time.Minute, 18).Sample(50*time.Minute, 0)
+				})
+			}
+		})
+	}
+
+	t.Run("identical objects should be merged once", func(t *testing.T) {
+		a := build(t, 0.32, 0)
+		merged, err := Merge(a, a.Clone())
+		if err != nil {
+			t.Fatalf("failed to merge: %v", err)
+		}
+		if !merged.Equal(a, 0) {
+			t.Errorf("expected merging a document with itself to change nothing, got %+v", Diff(a, merged))
+		}
+	})
+
+	t.Run("conflicting IDs should be renamed with their references", func(t *testing.T) {
+		a, b := build(t, 0.32, 0), build(t, 0.36, 0, 24*time.Hour)
+		merged, err := Merge(a, b)
+		if err != nil {
+			t.Fatalf("failed to merge: %v", err)
+		}
+
+		if n := len(merged.GasDefinitions.Mixes); n != 2 {
+			t.Fatalf("expected 2 mixes, got %d", n)
+		}
+		if id := merged.GasDefinitions.Mixes[1].ID; id != "mix-bottom-2" {
+			t.Errorf("expected renamed mix 'mix-bottom-2', got '%s'", id)
+		}
+		if n := len(merged.DiveSite.Sites); n != 1 {
+			t.Errorf("expected the identical site once, got %d", n)
+		}
+
+		groups := merged.ProfileData.RepetitionGroup
+		if len(groups) != 2 || len(groups[1].Dives) != 2 {
+			t.Fatalf("expected the dives of b in a second group, got %+v", groups)
+		}
+		dive := groups[1].Dives[0]
+		if dive.ID != "dive-1-2" || dive.TankData[0].Links[0].Ref != "mix-bottom-2" {
+			t.Errorf("expected renamed dive and mix reference, got %s -> %s", dive.ID, dive.TankData[0].Links[0].Ref)
+		}
+		if issues := merged.Check(); HasErrors(issues) {
+			t.Errorf("expected merged document without errors, got %v", issues)
+		}
+	})
+}
@@ -1,6 +1,9 @@
 package uddf
 
+import "encoding/xml"
+
 type UDDF struct {
+	XMLName             xml.Name             `xml:"uddf"`
 	Version             string               `xml:"version,attr"`
 	Business            *Business            `xml:"business,omitempty"`
 	DecoModel           *DecoModel           `xml:"decomodel,omitempty"`
@@ -542,7 +545,7 @@ type Site struct {
 	Name        string     `xml:"name"`
 	Notes       *Notes     `xml:"notes,omitempty"`
 	Ratings     []Rating   `xml:"rating,omitempty"`
-	SideData    *SiteData  `xml:"sidedata,omitempty"`
+	SideData    *SiteData  `xml:"sitedata,omitempty"`
 }
 
 type SiteData struct {
@@ -882,7 +885,7 @@ type EquipmentConfiguration struct {
 type EquipmentContent struct {
 	Boots                  []EquipmentPart `xml:"boots"`
 	BuoyancyControlDevices []EquipmentPart `xml:"buoyancycontroldevice"`
-	Cameras                []EquipmentPart `xml:"camera"`
+	Cameras                []Camera        `xml:"camera"`
 	Compasses              []EquipmentPart `xml:"compass"`
 	DiveComputers          []EquipmentPart `xml:"divecomputer"`
 	Fins                   []EquipmentPart `xml:"fins"`
@@ -964,7 +967,7 @@ type Manufacturer struct {
 }
 
 type Camera struct {
-	Body    EquipmentPart   `xml:"body"`
+	Body    *EquipmentPart  `xml:"body,omitempty"`
 	Flashes []EquipmentPart `xml:"flash"`
 	Housing *EquipmentPart  `xml:"housing,omitempty"`
 	Lens    *EquipmentPart  `xml:"lens,omitempty"`
@@ -0,0 +1,398 @@
+package uddf
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// minimumPo2 is the lowest oxygen partial pressure in bar a mix must provide
+// to be considered breathable.
+const minimumPo2 = 0.16
+
+// Segment is a level portion of a planned dive: the diver travels to Depth
+// and stays there for Duration seconds.
+type Segment struct {
+	Depth    float64 // metres
+	Duration float64 // seconds spent at Depth, excluding travel
+	MixRef   string  // ID of the mix breathed, empty keeps the current mix
+}
+
+// PlanOptions tune the dive planner. Zero values select the default given in
+// brackets.
+type PlanOptions struct {
+	DescentRate   float64 // m/s [0.3, i.e. 18 m/min]
+	AscentRate    float64 // m/s [0.15, i.e. 9 m/min]
+	StopInterval  float64 // distance between decompression stops in metres [3]
+	LastStopDepth float64 // metres [3]
+	StopTimeStep  float64 // granularity of stop durations in seconds [60]
+	MaximumPo2    float64 // oxygen partial pressure limit for bottom mixes in bar [1.4]
+	DecoPo2       float64 // oxygen partial pressure limit for decompression mixes in bar [1.6]
+	Environment   Environment
+}
+
+func (o PlanOptions) withDefaults() PlanOptions {
+	setDefault(&o.DescentRate, 0.3)
+	setDefault(&o.AscentRate, 0.15)
+	setDefault(&o.StopInterval, 3)
+	setDefault(&o.LastStopDepth, 3)
+	setDefault(&o.StopTimeStep, 60)
+	setDefault(&o.MaximumPo2, 1.4)
+	setDefault(&o.DecoPo2, 1.6)
+	return o
+}
+
+func setDefault(v *float64, def float64) {
+	if *v <= 0 {
+		*v = def
+	}
+}
+
+// Plan is a dive profile including its decompression schedule.
+type Plan struct {
+	StartMix  string
+	Model     Buehlmann
+	Waypoints []Waypoint   // complete profile from leaving the surface to surfacing
+	DecoStops []Decostop   // mandatory stops in the order they are made
+	Runtime   float64      // total dive time in seconds
+	Tissues   *TissueState // tissue loading on surfacing
+
+	ascentStart int // index of the first waypoint of the final ascent
+}
+
+// PlanDive computes the profile and ascent schedule for the given bottom
+// segments. On ascent the planner switches to the mix with the highest
+// oxygen fraction whose maximum operation depth at PlanOptions.DecoPo2 has
+// been reached. Stops are placed using the gradient factors of params.
+func PlanDive(segments []Segment, mixes []Mix, params Buehlmann, opts PlanOptions) (*Plan, error) {
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("dive plan needs at least one segment")
+	}
+	if len(mixes) == 0 {
+		return nil, fmt.Errorf("dive plan needs at least one mix")
+	}
+
+	gfLow, gfHigh := params.GradientFactors()
+	if gfLow <= 0 || gfHigh <= 0 || gfLow > gfHigh || gfHigh > 1 {
+		return nil, fmt.Errorf("invalid gradient factors %.2f/%.2f", gfLow, gfHigh)
+	}
+
+	opts = opts.withDefaults()
+	p := &planner{
+		opts:   opts,
+		mixes:  mixes,
+		state:  NewTissueState(&params, opts.Environment.Surface()),
+		gfLow:  gfLow,
+		gfHigh: gfHigh,
+	}
+
+	for i, segment := range segments {
+		if segment.Depth <= 0 || segment.Duration < 0 {
+			return nil, fmt.Errorf("segment %d: invalid depth %.1f m or duration %.0f s", i+1, segment.Depth, segment.Duration)
+		}
+
+		mix, err := p.segmentMix(segment)
+		if err != nil {
+			return nil, fmt.Errorf("segment %d: %w", i+1, err)
+		}
+		if mix != p.mix {
+			p.switchTo(mix)
+		}
+
+		p.travel(segment.Depth)
+		p.waypoint()
+		p.stay(segment.Duration)
+		p.waypoint()
+	}
+
+	plan := &Plan{
+		StartMix:    p.startMix,
+		Model:       params,
+		ascentStart: len(p.waypoints) - 1,
+	}
+
+	stops, err := p.ascend()
+	if err != nil {
+		return nil, err
+	}
+
+	plan.Waypoints = p.waypoints
+	plan.DecoStops = stops
+	plan.Runtime = p.time
+	plan.Tissues = p.state
+	return plan, nil
+}
+
+type planner struct {
+	opts          PlanOptions
+	mixes         []Mix
+	state         *TissueState
+	gfLow, gfHigh float64
+
+	depth     float64
+	time      float64
+	mix       *Mix
+	startMix  string
+	waypoints []Waypoint
+}
+
+func (p *planner) segmentMix(segment Segment) (*Mix, error) {
+	if segment.MixRef == "" {
+		if p.mix != nil {
+			return p.mix, nil
+		}
+		mix := bestMix(p.mixes, p.pressure(segment.Depth), p.opts.MaximumPo2)
+		if mix == nil {
+			return nil, fmt.Errorf("no breathable mix for %.1f m", segment.Depth)
+		}
+		return mix, nil
+	}
+
+	for i := range p.mixes {
+		if p.mixes[i].ID == segment.MixRef {
+			mix := &p.mixes[i]
+			if po2 := mix.Fractions().O2 * p.pressure(segment.Depth); po2 > p.opts.MaximumPo2+1e-9 {
+				return nil, fmt.Errorf("mix %s exceeds maximum ppO2 at %.1f m (%.2f bar)", mix.ID, segment.Depth, po2)
+			}
+			return mix, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown mix %q", segment.MixRef)
+}
+
+// bestMix returns the mix with the highest oxygen fraction that is
+// breathable at the ambient pressure in bar without exceeding maxPo2.
+func bestMix(mixes []Mix, pressure, maxPo2 float64) *Mix {
+	var best *Mix
+	for i := range mixes {
+		f := mixes[i].Fractions()
+		po2 := f.O2 * pressure
+		if po2 > maxPo2+1e-9 || po2 < minimumPo2 {
+			continue
+		}
+		if best == nil {
+			best = &mixes[i]
+			continue
+		}
+		bf := best.Fractions()
+		if f.O2 > bf.O2 || (f.O2 == bf.O2 && f.He > bf.He) {
+			best = &mixes[i]
+		}
+	}
+	return best
+}
+
+// waypoint returns the waypoint at the current depth and time, adding one
+// if the profile does not end there yet.
+func (p *planner) waypoint() *Waypoint {
+	if n := len(p.waypoints); n > 0 {
+		last := &p.waypoints[n-1]
+		if last.DiveTime == p.time && last.Depth == p.depth {
+			return last
+		}
+	}
+	p.waypoints = append(p.waypoints, Waypoint{Depth: p.depth, DiveTime: p.time})
+	return &p.waypoints[len(p.waypoints)-1]
+}
+
+func (p *planner) switchTo(mix *Mix) {
+	if p.mix == nil {
+		p.startMix = mix.ID
+	}
+	p.mix = mix
+	p.waypoint().SwitchMix = &SwitchMix{Ref: mix.ID}
+}
+
+func (p *planner) pressure(depth float64) float64 {
+	return p.opts.Environment.Pressure(depth)
+}
+
+func (p *planner) travel(depth float64) {
+	rate := p.opts.DescentRate
+	if depth < p.depth {
+		rate = p.opts.AscentRate
+	}
+	duration := math.Abs(depth-p.depth) / rate
+
+	p.state.Expose(p.pressure(p.depth), p.pressure(depth), duration, p.mix.Fractions())
+	p.depth = depth
+	p.time += duration
+}
+
+func (p *planner) stay(duration float64) {
+	pressure := p.pressure(p.depth)
+	p.state.Expose(pressure, pressure, duration, p.mix.Fractions())
+	p.time += duration
+}
+
+// nextStop returns the next shallower level on the stop grid, or the
+// surface once the last stop has been passed.
+func (p *planner) nextStop() float64 {
+	interval := p.opts.StopInterval
+	next := math.Ceil(p.depth/interval-1e-9)*interval - interval
+	if next < p.opts.LastStopDepth-1e-9 {
+		return 0
+	}
+	return next
+}
+
+// gradientFactor returns the gradient factor in effect at depth. GF low
+// applies until the first stop has been found, from there it increases
+// linearly to GF high at the surface.
+func (p *planner) gradientFactor(depth, firstStop float64) float64 {
+	if firstStop <= 0 {
+		if depth == 0 {
+			return p.gfHigh
+		}
+		return p.gfLow
+	}
+	return p.gfHigh - (p.gfHigh-p.gfLow)*depth/firstStop
+}
+
+// maxDecoTime bounds the ascent in case the schedule does not converge.
+const maxDecoTime = 48 * 3600
+
+func (p *planner) ascend() ([]Decostop, error) {
+	var stops []Decostop
+	firstStop := 0.0
+	stopTime := 0.0
+	stopStart := -1
+
+	for p.depth > 0 {
+		if mix := bestMix(p.mixes, p.pressure(p.depth), p.opts.DecoPo2); mix != nil && mix.Fractions().O2 > p.mix.Fractions().O2 {
+			p.switchTo(mix)
+		}
+
+		next := p.nextStop()
+		trial := p.state.Clone()
+		duration := (p.depth - next) / p.opts.AscentRate
+		trial.Expose(p.pressure(p.depth), p.pressure(next), duration, p.mix.Fractions())
+
+		if trial.Ceiling(p.gradientFactor(next, firstStop)) <= p.pressure(next) {
+			if stopTime > 0 {
+				stop := Decostop{Kind: "mandatory", DecoDepth: p.depth, Duration: stopTime}
+				stops = append(stops, stop)
+				p.waypoints[stopStart].DecoStops = append(p.waypoints[stopStart].DecoStops, stop)
+				p.waypoint()
+				stopTime = 0
+			}
+
+			p.state = trial
+			p.depth = next
+			p.time += duration
+			continue
+		}
+
+		if firstStop <= 0 {
+			firstStop = p.depth
+		}
+		if stopTime == 0 {
+			p.waypoint()
+			stopStart = len(p.waypoints) - 1
+		}
+		p.stay(p.opts.StopTimeStep)
+		stopTime += p.opts.StopTimeStep
+
+		if p.time > maxDecoTime {
+			return nil, fmt.Errorf("decompression schedule does not converge")
+		}
+	}
+
+	p.waypoint()
+	return stops, nil
+}
+
+// maxNoDecoTime caps no-decompression limits, which are practically
+// unlimited in shallow water.
+const maxNoDecoTime = 6 * 3600
+
+// NoDecoLimit returns the longest bottom time in whole minutes, counted from
+// leaving the surface, for which PlanDive yields no decompression stops at
+// depth breathing mix. The result is given in seconds and capped at six
+// hours.
+func NoDecoLimit(depth float64, mix Mix, params Buehlmann, opts PlanOptions) (float64, error) {
+	opts = opts.withDefaults()
+	descent := depth / opts.DescentRate
+	mixes := []Mix{mix}
+
+	noStops := func(bottomTime float64) (bool, error) {
+		segment := Segment{Depth: depth, Duration: math.Max(bottomTime-descent, 0), MixRef: mix.ID}
+		plan, err := PlanDive([]Segment{segment}, mixes, params, opts)
+		if err != nil {
+			return false, err
+		}
+		return len(plan.DecoStops) == 0, nil
+	}
+
+	// Binary search over whole minutes
+	low, high := 0, maxNoDecoTime/60
+	for low < high {
+		mid := (low + high + 1) / 2
+		ok, err := noStops(float64(mid) * 60)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			low = mid
+		} else {
+			high = mid - 1
+		}
+	}
+	return float64(low) * 60, nil
+}
+
+// PlannedProfile returns the plan in the form stored in
+// InformationBeforeDive.
+func (p *Plan) PlannedProfile() *PlannedProfile {
+	return &PlannedProfile{
+		StartDiveMode: "opencircuit",
+		StartMix:      p.StartMix,
+		Waypoints:     p.Waypoints,
+	}
+}
+
+// Profile returns the plan as a calculated profile for
+// TableGeneration.CalculateProfile. The bottom segments form the input
+// profile, gas switches are listed under MixChange and the printed schedule
+// is stored in the output remark.
+func (p *Plan) Profile() Profile {
+	model := p.Model
+	profile := Profile{
+		DecoModel:    &DecoModel{Buehlmann: []Buehlmann{model}},
+		InputProfile: &InputProfile{Waypoints: p.Waypoints[:p.ascentStart+1]},
+		Output: &Output{
+			Headline: ptr(fmt.Sprintf("Runtime %.0f min, %d decompression stops", math.Ceil(p.Runtime/60), len(p.DecoStops))),
+			Remark:   ptr(p.String()),
+		},
+	}
+
+	for i, w := range p.Waypoints {
+		if w.SwitchMix == nil {
+			continue
+		}
+		if i <= p.ascentStart {
+			profile.MixChange.Descent.Waypoints = append(profile.MixChange.Descent.Waypoints, w)
+		} else {
+			profile.MixChange.Ascent.Waypoints = append(profile.MixChange.Ascent.Waypoints, w)
+		}
+	}
+	return profile
+}
+
+// String renders the plan as a runtime table.
+func (p *Plan) String() string {
+	var b strings.Builder
+	b.WriteString("Depth   Stop  Runtime  Mix\n")
+	for _, w := range p.Waypoints {
+		stop := "     "
+		if len(w.DecoStops) > 0 {
+			stop = fmt.Sprintf("%3.0f'", w.DecoStops[0].Duration/60)
+		}
+		mix := ""
+		if w.SwitchMix != nil {
+			mix = w.SwitchMix.Ref
+		}
+		fmt.Fprintf(&b, "%4.0fm  %s  %5.1f'  %s\n", w.Depth, stop, w.DiveTime/60, mix)
+	}
+	return b.String()
+}
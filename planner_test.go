@@ -0,0 +1,91 @@
+package uddf
+
+import "testing"
+
+func TestPlanDive(t *testing.T) {
+	mixes := []Mix{
+		{ID: "tx2135", Name: "Trimix 21/35", O2: ptr(0.21), He: ptr(0.35)},
+		{ID: "ean50", Name: "Nitrox 50", O2: ptr(0.5)},
+		{ID: "o2", Name: "Oxygen", O2: ptr(1.0)},
+	}
+	params := Buehlmann{GradientFactorLow: ptr(0.3), GradientFactorHigh: ptr(0.85)}
+
+	t.Run("short shallow dive should need no stops", func(t *testing.T) {
+		plan, err := PlanDive([]Segment{{Depth: 12, Duration: 30 * 60}}, []Mix{{ID: "air", Name: "Air"}}, params, PlanOptions{})
+		if err != nil {
+			t.Fatalf("failed to plan dive: %v", err)
+		}
+
+		if len(plan.DecoStops) != 0 {
+			t.Errorf("expected no decompression stops, got %v", plan.DecoStops)
+		}
+		if plan.StartMix != "air" {
+			t.Errorf("expected start mix 'air', got '%s'", plan.StartMix)
+		}
+	})
+
+	t.Run("deep trimix dive should produce stops and gas switches", func(t *testing.T) {
+		plan, err := PlanDive([]Segment{{Depth: 50, Duration: 20 * 60, MixRef: "tx2135"}}, mixes, params, PlanOptions{})
+		if err != nil {
+			t.Fatalf("failed to plan dive: %v", err)
+		}
+
+		if len(plan.DecoStops) == 0 {
+			t.Fatal("expected decompression stops")
+		}
+		for i := 1; i < len(plan.DecoStops); i++ {
+			if plan.DecoStops[i].DecoDepth >= plan.DecoStops[i-1].DecoDepth {
+				t.Errorf("expected stops to get shallower, got %v", plan.DecoStops)
+			}
+		}
+
+		switches := map[string]float64{}
+		for _, w := range plan.Waypoints {
+			if w.SwitchMix != nil {
+				switches[w.SwitchMix.Ref] = w.Depth
+			}
+		}
+		if depth, ok := switches["ean50"]; !ok || depth != 21 {
+			t.Errorf("expected switch to ean50 at 21 m, got %v", switches)
+		}
+		if depth, ok := switches["o2"]; !ok || depth != 3 {
+			t.Errorf("expected switch to o2 at 3 m, got %v", switches)
+		}
+
+		last := plan.Waypoints[len(plan.Waypoints)-1]
+		if last.Depth != 0 || last.DiveTime != plan.Runtime {
+			t.Errorf("expected plan to end at the surface after %v s, got %+v", plan.Runtime, last)
+		}
+
+		profile := plan.Profile()
+		if len(profile.MixChange.Ascent.Waypoints) != 2 {
+			t.Errorf("expected 2 ascent gas switches, got %d", len(profile.MixChange.Ascent.Waypoints))
+		}
+		if profile.Output == nil || profile.Output.Remark == nil {
+			t.Error("expected the schedule in the profile output")
+		}
+	})
+
+	t.Run("lower gradient factors should lengthen decompression", func(t *testing.T) {
+		segments := []Segment{{Depth: 40, Duration: 25 * 60, MixRef: "tx2135"}}
+		conservative, err := PlanDive(segments, mixes, Buehlmann{GradientFactorLow: ptr(0.2), GradientFactorHigh: ptr(0.7)}, PlanOptions{})
+		if err != nil {
+			t.Fatalf("failed to plan dive: %v", err)
+		}
+		liberal, err := PlanDive(segments, mixes, Buehlmann{GradientFactorLow: ptr(0.8), GradientFactorHigh: ptr(0.95)}, PlanOptions{})
+		if err != nil {
+			t.Fatalf("failed to plan dive: %v", err)
+		}
+
+		if conservative.Runtime <= liberal.Runtime {
+			t.Errorf("expected GF 20/70 runtime %v to exceed GF 80/95 runtime %v", conservative.Runtime, liberal.Runtime)
+		}
+	})
+
+	t.Run("mix exceeding maximum ppO2 should fail", func(t *testing.T) {
+		_, err := PlanDive([]Segment{{Depth: 30, Duration: 600, MixRef: "ean50"}}, mixes, params, PlanOptions{})
+		if err == nil {
+			t.Error("expected error for ean50 at 30 m, got nil")
+		}
+	})
+}
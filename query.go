@@ -0,0 +1,667 @@
+package uddf
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Query is a compiled path expression over the Go model of a document. It
+// supports a subset of XPath:
+//
+//	/uddf/gasdefinitions/mix             child steps from the root
+//	//dive                               descendants at any depth
+//	//dive/@id                           attributes
+//	//mix/*  //mix/..  //mix/.           any child, parent, self
+//	//dive[2]                            position among the step's matches
+//	//dive[informationafterdive/greatestdepth > 40]
+//	//site[geography/location contains 'Egypt']
+//	//mix[o2 >= 0.3 and not(he)]
+//
+// Predicates compare with =, !=, <, <=, >, >= and contains, combine with
+// and, or and not(), and treat a path as true if it selects anything. As in
+// XPath, a comparison involving a path holds if it holds for any selected
+// node. Numbers are compared numerically, everything else as text.
+type Query struct {
+	expr string
+	path queryPath
+}
+
+// CompileQuery parses expr.
+func CompileQuery(expr string) (*Query, error) {
+	p := &queryParser{tokens: lexQuery(expr)}
+	path, err := p.path()
+	if err == nil && p.peek().kind != tokEOF {
+		err = fmt.Errorf("unexpected %q", p.peek().text)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse query %q: %w", expr, err)
+	}
+	return &Query{expr: expr, path: path}, nil
+}
+
+func (q *Query) String() string {
+	return q.expr
+}
+
+// Select returns the nodes of u matched by the query in document order.
+// Node values point into u.
+func (q *Query) Select(u *UDDF) []*Node {
+	if u == nil {
+		return nil
+	}
+	doc := &queryNode{children: []*queryNode{{
+		node: &Node{Path: "/uddf", Name: "uddf", Value: u},
+		v:    reflect.ValueOf(u).Elem(),
+	}}}
+	doc.children[0].parent = doc
+
+	var nodes []*Node
+	for _, m := range q.path.eval(doc, doc) {
+		if m.node != nil {
+			nodes = append(nodes, m.node)
+		}
+	}
+	return nodes
+}
+
+// Query compiles expr and returns the matching nodes of u.
+func (u *UDDF) Query(expr string) ([]*Node, error) {
+	q, err := CompileQuery(expr)
+	if err != nil {
+		return nil, err
+	}
+	return q.Select(u), nil
+}
+
+// Text returns the character data of the node's value: the value itself for
+// attributes and simple elements, times as written to documents (see
+// Time.String) and "" for elements without character data.
+func (n *Node) Text() string {
+	return nodeText(reflect.ValueOf(n.Value))
+}
+
+func nodeText(v reflect.Value) string {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+
+	switch v.Type() {
+	case timeType:
+		return v.Interface().(Time).String()
+	case flexibleFloatType:
+		return nodeText(v.Field(0))
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Struct:
+		for i := range v.NumField() {
+			field := v.Type().Field(i)
+			if name, ok := xmlFieldName(field); ok && name == "" && field.IsExported() && !field.Anonymous {
+				return nodeText(v.Field(i))
+			}
+		}
+	}
+	return ""
+}
+
+// queryNode is a Node together with its value and, once expanded, its
+// children. The document node above the root has no value.
+type queryNode struct {
+	node     *Node
+	v        reflect.Value
+	parent   *queryNode
+	children []*queryNode
+	expanded bool
+}
+
+func (n *queryNode) kids() []*queryNode {
+	if n.expanded || !n.v.IsValid() {
+		return n.children
+	}
+	n.expanded = true
+	n.addChildren(n.v)
+	return n.children
+}
+
+func (n *queryNode) addChildren(v reflect.Value) {
+	if v.Kind() != reflect.Struct || v.Type() == timeType || v.Type() == flexibleFloatType {
+		return
+	}
+
+	for i := range v.NumField() {
+		field := v.Type().Field(i)
+		if !field.IsExported() || field.Name == "XMLName" {
+			continue
+		}
+		if field.Anonymous {
+			n.addChildren(v.Field(i))
+			continue
+		}
+		name, ok := xmlFieldName(field)
+		if !ok || name == "" {
+			continue
+		}
+
+		path := n.node.Path + "/" + name
+		fv := v.Field(i)
+		switch fv.Kind() {
+		case reflect.Pointer:
+			if !fv.IsNil() {
+				n.add(path, name, fv.Interface(), fv.Elem())
+			}
+		case reflect.Slice:
+			for j := range fv.Len() {
+				elem := fv.Index(j)
+				n.add(fmt.Sprintf("%s[%d]", path, j+1), name, elem.Addr().Interface(), elem)
+			}
+		default:
+			n.add(path, name, fv.Addr().Interface(), fv)
+		}
+	}
+}
+
+func (n *queryNode) add(path, name string, value any, v reflect.Value) {
+	child := &queryNode{node: &Node{Path: path, Name: name, Value: value, Parent: n.node}, v: v, parent: n}
+	n.children = append(n.children, child)
+}
+
+func (n *queryNode) isAttr() bool {
+	return strings.HasPrefix(n.node.Name, "@")
+}
+
+func (n *queryNode) text() string {
+	if n.node == nil {
+		return ""
+	}
+	return n.node.Text()
+}
+
+// descendants returns n and all nodes below it in document order.
+func (n *queryNode) descendants() []*queryNode {
+	nodes := []*queryNode{n}
+	for _, c := range n.kids() {
+		if !c.isAttr() {
+			nodes = append(nodes, c.descendants()...)
+		}
+	}
+	return nodes
+}
+
+type queryPath struct {
+	absolute bool
+	steps    []queryStep
+}
+
+type queryStep struct {
+	descendant bool   // preceded by //
+	name       string // element name, "@name", "*", "@*", "." or ".."
+	predicates []queryExpr
+}
+
+func (p queryPath) eval(ctx, doc *queryNode) []*queryNode {
+	nodes := []*queryNode{ctx}
+	if p.absolute {
+		nodes = []*queryNode{doc}
+	}
+	for _, step := range p.steps {
+		nodes = step.eval(nodes, doc)
+	}
+	return nodes
+}
+
+func (s queryStep) eval(contexts []*queryNode, doc *queryNode) []*queryNode {
+	var result []*queryNode
+	seen := map[*queryNode]bool{}
+	for _, ctx := range contexts {
+		bases := []*queryNode{ctx}
+		if s.descendant {
+			bases = ctx.descendants()
+		}
+
+		var candidates []*queryNode
+		for _, base := range bases {
+			candidates = append(candidates, s.candidates(base)...)
+		}
+		for _, pred := range s.predicates {
+			var kept []*queryNode
+			for i, c := range candidates {
+				if testExpr(pred, c, i+1, doc) {
+					kept = append(kept, c)
+				}
+			}
+			candidates = kept
+		}
+
+		for _, c := range candidates {
+			if !seen[c] {
+				seen[c] = true
+				result = append(result, c)
+			}
+		}
+	}
+	return result
+}
+
+func (s queryStep) candidates(n *queryNode) []*queryNode {
+	switch s.name {
+	case ".":
+		return []*queryNode{n}
+	case "..":
+		if n.parent == nil {
+			return nil
+		}
+		return []*queryNode{n.parent}
+	}
+
+	var matches []*queryNode
+	for _, c := range n.kids() {
+		name := c.node.Name
+		switch {
+		case s.name == "*" && !c.isAttr(), s.name == "@*" && c.isAttr(), s.name == name:
+			matches = append(matches, c)
+		}
+	}
+	return matches
+}
+
+// queryExpr is a predicate expression. It evaluates to a node set, a
+// string, a float64 or a bool.
+type queryExpr interface {
+	eval(ctx *queryNode, doc *queryNode) any
+}
+
+type (
+	pathExpr    struct{ path queryPath }
+	literalExpr struct{ value any }
+	notExpr     struct{ expr queryExpr }
+	binaryExpr  struct {
+		op          string
+		left, right queryExpr
+	}
+)
+
+func (e pathExpr) eval(ctx, doc *queryNode) any    { return e.path.eval(ctx, doc) }
+func (e literalExpr) eval(ctx, doc *queryNode) any { return e.value }
+func (e notExpr) eval(ctx, doc *queryNode) any     { return !truth(e.expr.eval(ctx, doc)) }
+
+func (e binaryExpr) eval(ctx, doc *queryNode) any {
+	switch e.op {
+	case "and":
+		return truth(e.left.eval(ctx, doc)) && truth(e.right.eval(ctx, doc))
+	case "or":
+		return truth(e.left.eval(ctx, doc)) || truth(e.right.eval(ctx, doc))
+	}
+
+	left, right := operands(e.left.eval(ctx, doc)), operands(e.right.eval(ctx, doc))
+	for _, l := range left {
+		for _, r := range right {
+			if compare(e.op, l, r) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// testExpr evaluates e as a predicate. A number selects the node
+// at that position.
+func testExpr(e queryExpr, n *queryNode, position int, doc *queryNode) bool {
+	v := e.eval(n, doc)
+	if f, ok := v.(float64); ok {
+		return int(f) == position
+	}
+	return truth(v)
+}
+
+// truth converts a value to a boolean the way XPath does.
+func truth(v any) bool {
+	switch v := v.(type) {
+	case bool:
+		return v
+	case []*queryNode:
+		return len(v) > 0
+	case string:
+		return v != ""
+	case float64:
+		return v != 0
+	}
+	return false
+}
+
+// operands returns the values a comparison is applied to: the text of every
+// node of a node set, or the value itself.
+func operands(v any) []any {
+	nodes, ok := v.([]*queryNode)
+	if !ok {
+		return []any{v}
+	}
+	values := make([]any, len(nodes))
+	for i, n := range nodes {
+		values[i] = n.text()
+	}
+	return values
+}
+
+func compare(op string, l, r any) bool {
+	if op == "contains" {
+		return strings.Contains(fmt.Sprint(l), fmt.Sprint(r))
+	}
+
+	// Compare numerically if either side is a number
+	_, lNum := l.(float64)
+	_, rNum := r.(float64)
+	if lNum || rNum {
+		a, errA := toNumber(l)
+		b, errB := toNumber(r)
+		if errA != nil || errB != nil {
+			return op == "!="
+		}
+		switch op {
+		case "=":
+			return a == b
+		case "!=":
+			return a != b
+		case "<":
+			return a < b
+		case "<=":
+			return a <= b
+		case ">":
+			return a > b
+		case ">=":
+			return a >= b
+		}
+		return false
+	}
+
+	a, b := fmt.Sprint(l), fmt.Sprint(r)
+	switch op {
+	case "=":
+		return a == b
+	case "!=":
+		return a != b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	}
+	return false
+}
+
+func toNumber(v any) (float64, error) {
+	switch v := v.(type) {
+	case float64:
+		return v, nil
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	}
+	return strconv.ParseFloat(strings.TrimSpace(fmt.Sprint(v)), 64)
+}
+
+const (
+	tokEOF = iota
+	tokName
+	tokNumber
+	tokString
+	tokSymbol
+)
+
+type queryToken struct {
+	kind int
+	text string
+}
+
+func lexQuery(s string) []queryToken {
+	var tokens []queryToken
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '\'' || c == '"':
+			end := strings.IndexByte(s[i+1:], c)
+			if end < 0 {
+				tokens = append(tokens, queryToken{kind: tokSymbol, text: s[i:]})
+				i = len(s)
+				continue
+			}
+			tokens = append(tokens, queryToken{kind: tokString, text: s[i+1 : i+1+end]})
+			i += end + 2
+		case c >= '0' && c <= '9' || c == '.' && i+1 < len(s) && s[i+1] >= '0' && s[i+1] <= '9':
+			j := i + 1
+			for j < len(s) && (s[j] >= '0' && s[j] <= '9' || s[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, queryToken{kind: tokNumber, text: s[i:j]})
+			i = j
+		case c == '_' || unicode.IsLetter(rune(c)):
+			j := i + 1
+			for j < len(s) && (s[j] == '_' || s[j] == '-' || unicode.IsLetter(rune(s[j])) || s[j] >= '0' && s[j] <= '9') {
+				j++
+			}
+			tokens = append(tokens, queryToken{kind: tokName, text: s[i:j]})
+			i = j
+		default:
+			symbol := s[i : i+1]
+			for _, two := range []string{"//", "..", "!=", "<=", ">="} {
+				if strings.HasPrefix(s[i:], two) {
+					symbol = two
+					break
+				}
+			}
+			tokens = append(tokens, queryToken{kind: tokSymbol, text: symbol})
+			i += len(symbol)
+		}
+	}
+	return append(tokens, queryToken{kind: tokEOF})
+}
+
+type queryParser struct {
+	tokens []queryToken
+	pos    int
+}
+
+func (p *queryParser) peek() queryToken {
+	return p.tokens[p.pos]
+}
+
+func (p *queryParser) next() queryToken {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *queryParser) accept(kind int, text string) bool {
+	if t := p.peek(); t.kind == kind && t.text == text {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *queryParser) expect(text string) error {
+	if !p.accept(tokSymbol, text) {
+		return fmt.Errorf("expected %q, got %q", text, p.peek().text)
+	}
+	return nil
+}
+
+func (p *queryParser) path() (queryPath, error) {
+	var path queryPath
+	descendant := false
+	switch {
+	case p.accept(tokSymbol, "//"):
+		path.absolute, descendant = true, true
+	case p.accept(tokSymbol, "/"):
+		path.absolute = true
+	}
+
+	for {
+		step, err := p.step()
+		if err != nil {
+			return path, err
+		}
+		step.descendant = descendant
+		path.steps = append(path.steps, step)
+
+		switch {
+		case p.accept(tokSymbol, "//"):
+			descendant = true
+		case p.accept(tokSymbol, "/"):
+			descendant = false
+		default:
+			return path, nil
+		}
+	}
+}
+
+func (p *queryParser) step() (queryStep, error) {
+	var step queryStep
+	t := p.next()
+	switch {
+	case t.kind == tokSymbol && t.text == "@":
+		name := p.next()
+		if name.kind != tokName && name.text != "*" {
+			return step, fmt.Errorf("expected attribute name, got %q", name.text)
+		}
+		step.name = "@" + name.text
+	case t.kind == tokName, t.kind == tokSymbol && (t.text == "*" || t.text == "." || t.text == ".."):
+		step.name = t.text
+	default:
+		return step, fmt.Errorf("expected step, got %q", t.text)
+	}
+
+	for p.accept(tokSymbol, "[") {
+		pred, err := p.or()
+		if err != nil {
+			return step, err
+		}
+		if err := p.expect("]"); err != nil {
+			return step, err
+		}
+		step.predicates = append(step.predicates, pred)
+	}
+	return step, nil
+}
+
+func (p *queryParser) or() (queryExpr, error) {
+	left, err := p.and()
+	for err == nil && p.accept(tokName, "or") {
+		var right queryExpr
+		right, err = p.and()
+		left = binaryExpr{op: "or", left: left, right: right}
+	}
+	return left, err
+}
+
+func (p *queryParser) and() (queryExpr, error) {
+	left, err := p.comparison()
+	for err == nil && p.accept(tokName, "and") {
+		var right queryExpr
+		right, err = p.comparison()
+		left = binaryExpr{op: "and", left: left, right: right}
+	}
+	return left, err
+}
+
+func (p *queryParser) comparison() (queryExpr, error) {
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+
+	t := p.peek()
+	switch {
+	case t.kind == tokSymbol && (t.text == "=" || t.text == "!=" || t.text == "<" || t.text == "<=" || t.text == ">" || t.text == ">="):
+	case t.kind == tokName && t.text == "contains":
+	default:
+		return left, nil
+	}
+	op := p.next().text
+
+	right, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	return binaryExpr{op: op, left: left, right: right}, nil
+}
+
+func (p *queryParser) operand() (queryExpr, error) {
+	t := p.peek()
+	switch {
+	case t.kind == tokString:
+		p.next()
+		return literalExpr{value: t.text}, nil
+	case t.kind == tokNumber:
+		p.next()
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", t.text)
+		}
+		return literalExpr{value: f}, nil
+	case t.kind == tokSymbol && t.text == "(":
+		p.next()
+		e, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		return e, p.expect(")")
+	case t.kind == tokName && p.tokens[p.pos+1].text == "(":
+		return p.function()
+	}
+
+	path, err := p.path()
+	if err != nil {
+		return nil, err
+	}
+	return pathExpr{path: path}, nil
+}
+
+func (p *queryParser) function() (queryExpr, error) {
+	name := p.next().text
+	p.next() // (
+
+	var args []queryExpr
+	for !p.accept(tokSymbol, ")") {
+		if len(args) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		arg, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+
+	switch {
+	case name == "not" && len(args) == 1:
+		return notExpr{expr: args[0]}, nil
+	case name == "contains" && len(args) == 2:
+		return binaryExpr{op: "contains", left: args[0], right: args[1]}, nil
+	}
+	return nil, fmt.Errorf("unknown function %s with %d arguments", name, len(args))
+}
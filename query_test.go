@@ -0,0 +1,90 @@
+package uddf
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestQuery(t *testing.T) {
+	start := time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC)
+	u := buildDocument(t, func(b *Builder) {
+		b.AddMix("Air", 0.21, 0).
+			AddMix("Trimix 18/45", 0.18, 0.45).
+			AddSite("Brothers, Egypt", 26.3, 34.8).
+			AddSite("Blue Hole, Belize", 17.3, -87.5).
+			AddDive(func(d *DiveBuilder) {
+				d.At(start).Site("Brothers, Egypt").Tank("Air", 0.012, 200e5, 60e5)
+				d.Sample(0, 0).Sample(20*time.Minute, 32).Sample(45*time.Minute, 0)
+			}).
+			AddDive(func(d *DiveBuilder) {
+				d.At(start.Add(4*time.Hour)).Site("Blue Hole, Belize").Tank("Trimix 18/45", 0.024, 220e5, 80e5)
+				d.Sample(0, 0).Sample(25*time.Minute, 55).Sample(70*time.Minute, 0)
+			})
+	})
+
+	texts := func(t *testing.T, expr string) []string {
+		t.Helper()
+		nodes, err := u.Query(expr)
+		if err != nil {
+			t.Fatalf("failed to query: %v", err)
+		}
+		var result []string
+		for _, n := range nodes {
+			result = append(result, n.Text())
+		}
+		return result
+	}
+
+	tests := []struct {
+		expr     string
+		expected string
+	}{
+		{"//dive[informationafterdive/greatestdepth > 40]/@id", "dive-2"},
+		{"//dive/@id", "dive-1,dive-2"},
+		{"/uddf/gasdefinitions/mix[2]/name", "Trimix 18/45"},
+		{"//site[geography/location contains 'Egypt']/name", "Brothers, Egypt"},
+		{"//mix[o2 >= 0.2 and not(he)]/name", "Air"},
+		{"//mix[he or name = 'Air']/@id", "mix-air,mix-trimix-18-45"},
+		{"//tankdata/link[@ref = 'mix-air']/../../@id", "dive-1"},
+		{"//dive[informationbeforedive/datetime = '2024-05-01T13:30:00Z']/@id", "dive-2"},
+		{"//repetitiongroup/*/@id", "dive-1,dive-2"},
+		{"//waypoint[depth = 55]/divetime", "1500"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			if got := strings.Join(texts(t, tt.expr), ","); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+
+	t.Run("nodes should be typed and point into the document", func(t *testing.T) {
+		nodes, err := u.Query("//dive[2]")
+		if err != nil {
+			t.Fatalf("failed to query: %v", err)
+		}
+		if len(nodes) != 1 {
+			t.Fatalf("expected 1 node, got %d", len(nodes))
+		}
+		dive, ok := nodes[0].Value.(*Dive)
+		if !ok {
+			t.Fatalf("expected *Dive, got %T", nodes[0].Value)
+		}
+		if dive != &u.ProfileData.RepetitionGroup[0].Dives[1] {
+			t.Error("expected node to point into the document")
+		}
+		if nodes[0].Path != "/uddf/profiledata/repetitiongroup[1]/dive[2]" {
+			t.Errorf("unexpected path %s", nodes[0].Path)
+		}
+	})
+
+	t.Run("invalid queries should fail to compile", func(t *testing.T) {
+		for _, expr := range []string{"//dive[", "//dive[@id = ]", "/", "//mix[foo(o2)]"} {
+			if _, err := CompileQuery(expr); err == nil {
+				t.Errorf("expected error for %q", expr)
+			}
+		}
+	})
+}
@@ -0,0 +1,126 @@
+package uddf
+
+import "math"
+
+// Virial coefficients of the compressibility factor at 20 °C,
+// Z = 1 + c[0]·p + c[1]·p² + c[2]·p³ with p in bar.
+var (
+	virialO2 = [3]float64{-7.18092073703e-04, 2.81852572808e-06, -1.50290620492e-09}
+	virialN2 = [3]float64{-2.19260353292e-04, 2.92844845532e-06, -2.07613482075e-09}
+	virialHe = [3]float64{4.87320026468e-04, -8.83632921053e-08, 5.33304543646e-11}
+)
+
+// Van der Waals constants a in Pa·m^6/mol^2 and b in m^3/mol.
+var (
+	vanDerWaalsO2 = [2]float64{0.1382, 3.186e-5}
+	vanDerWaalsN2 = [2]float64{0.1370, 3.870e-5}
+	vanDerWaalsHe = [2]float64{0.00346, 2.380e-5}
+	vanDerWaalsAr = [2]float64{0.1355, 3.201e-5}
+	vanDerWaalsH2 = [2]float64{0.02476, 2.661e-5}
+)
+
+// Compressibility returns the compressibility factor Z = pV/(nRT) of the gas
+// at pressure Pa and temperature K [20 °C]. At 20 °C it is computed from
+// virial coefficients fitted to measured data, mixing the factors of the
+// components linearly and counting argon as nitrogen and hydrogen as helium.
+// The change with temperature follows the van der Waals equation.
+func (f GasFractions) Compressibility(pressure, temperature float64) float64 {
+	setDefault(&temperature, standardTemperature)
+	bar := pressure / pascalPerBar
+	virial := func(c [3]float64) float64 {
+		return c[0]*bar + c[1]*bar*bar + c[2]*bar*bar*bar
+	}
+	z := 1 + f.O2*virial(virialO2) + (f.N2+f.Ar)*virial(virialN2) + (f.He+f.H2)*virial(virialHe)
+	if temperature != standardTemperature {
+		z *= f.vanDerWaals(pressure, temperature) / f.vanDerWaals(pressure, standardTemperature)
+	}
+	return z
+}
+
+// vanDerWaals returns the compressibility factor of the gas according to the
+// van der Waals equation with the usual mixing rules.
+func (f GasFractions) vanDerWaals(pressure, temperature float64) float64 {
+	components := []struct {
+		fraction float64
+		ab       [2]float64
+	}{{f.O2, vanDerWaalsO2}, {f.N2, vanDerWaalsN2}, {f.He, vanDerWaalsHe}, {f.Ar, vanDerWaalsAr}, {f.H2, vanDerWaalsH2}}
+	var a, b float64
+	for _, i := range components {
+		b += i.fraction * i.ab[1]
+		for _, j := range components {
+			a += i.fraction * j.fraction * math.Sqrt(i.ab[0]*j.ab[0])
+		}
+	}
+	if pressure <= 0 {
+		return 1
+	}
+
+	// Newton's method for the molar volume, starting from the ideal gas
+	rt := gasConstant * temperature
+	v := rt / pressure
+	for range 50 {
+		g := (pressure+a/(v*v))*(v-b) - rt
+		dg := pressure + a/(v*v) - 2*a*(v-b)/(v*v*v)
+		step := g / dg
+		v -= step
+		if math.Abs(step) < v*1e-12 {
+			break
+		}
+	}
+	return pressure * v / rt
+}
+
+// FreeGasVolume returns the volume in m^3 at 1.01325 bar and 20 °C of the
+// gas in a tank of the given water volume in m^3 at pressure Pa and
+// temperature K [20 °C].
+func (f GasFractions) FreeGasVolume(waterVolume, pressure, temperature float64) float64 {
+	if pressure <= 0 {
+		return 0
+	}
+	setDefault(&temperature, standardTemperature)
+	surface := seaLevelPressure * pascalPerBar
+	moles := pressure * waterVolume / (f.Compressibility(pressure, temperature) * gasConstant * temperature)
+	return moles * f.Compressibility(surface, standardTemperature) * gasConstant * standardTemperature / surface
+}
+
+// FillPressure returns the pressure in Pa at temperature K [20 °C] of a tank
+// of the given water volume in m^3 holding freeGas m^3 of the gas, the
+// inverse of FreeGasVolume.
+func (f GasFractions) FillPressure(waterVolume, freeGas, temperature float64) float64 {
+	if waterVolume <= 0 || freeGas <= 0 {
+		return 0
+	}
+	// Z changes slowly with pressure, so fixed-point iteration converges
+	ideal := freeGas / f.FreeGasVolume(waterVolume, pascalPerBar, temperature) * pascalPerBar
+	pressure := ideal
+	for range 50 {
+		next := pressure * freeGas / f.FreeGasVolume(waterVolume, pressure, temperature)
+		if math.Abs(next-pressure) < 1 {
+			return next
+		}
+		pressure = next
+	}
+	return pressure
+}
+
+// FreeGas returns the free gas in m^3 the tank holds when filled with mix at
+// pressure Pa and temperature K [20 °C]. A nil mix is taken to be air. It
+// returns zero if the tank volume is unknown.
+func (t *Tank) FreeGas(mix *Mix, pressure, temperature float64) float64 {
+	return mixFractions(mix).FreeGasVolume(deref(t.TankVolume), pressure, temperature)
+}
+
+// FreeGas returns the free gas in m^3 the tank holds when filled with mix at
+// pressure Pa and temperature K [20 °C]. A nil mix is taken to be air. It
+// returns zero if the tank volume is unknown.
+func (t *TankData) FreeGas(mix *Mix, pressure, temperature float64) float64 {
+	return mixFractions(mix).FreeGasVolume(deref(t.TankVolume), pressure, temperature)
+}
+
+// mixFractions returns the fractions of m, those of air if m is nil.
+func mixFractions(m *Mix) GasFractions {
+	if m == nil {
+		return Air
+	}
+	return m.Fractions()
+}
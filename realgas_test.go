@@ -0,0 +1,62 @@
+package uddf
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRealGas(t *testing.T) {
+	trimix := GasFractions{O2: 0.18, He: 0.45, N2: 0.37}
+
+	t.Run("compressibility should match measured values", func(t *testing.T) {
+		for _, tc := range []struct {
+			gas       GasFractions
+			pressure  float64
+			low, high float64
+		}{
+			{Air, 1e5, 0.999, 1.001},
+			{Air, 200e5, 1.03, 1.04},
+			{GasFractions{He: 1}, 200e5, 1.09, 1.10},
+		} {
+			if z := tc.gas.Compressibility(tc.pressure, 0); z < tc.low || z > tc.high {
+				t.Errorf("expected Z of %s at %.0f bar in [%v, %v], got %v", tc.gas.Name(), tc.pressure/1e5, tc.low, tc.high, z)
+			}
+		}
+	})
+
+	t.Run("ideal gas math should overestimate contents", func(t *testing.T) {
+		ideal := 0.012 * 300 / seaLevelPressure
+		air, tmx := Air.FreeGasVolume(0.012, 300e5, 0), trimix.FreeGasVolume(0.012, 300e5, 0)
+		if air >= ideal || tmx >= air {
+			t.Errorf("expected trimix %v < air %v < ideal %v", tmx, air, ideal)
+		}
+	})
+
+	t.Run("warm tanks should hold less gas", func(t *testing.T) {
+		warm := Air.FreeGasVolume(0.012, 230e5, zeroCelsius+40)
+		room := Air.FreeGasVolume(0.012, 230e5, 0)
+		if ratio := warm / room; ratio > 0.95 || ratio < 0.9 {
+			t.Errorf("expected about 7%% less gas at 40 °C, got %.3f", ratio)
+		}
+	})
+
+	t.Run("fill pressure should invert the free gas volume", func(t *testing.T) {
+		for _, gas := range []GasFractions{Air, trimix} {
+			free := gas.FreeGasVolume(0.0111, 232e5, zeroCelsius+10)
+			if p := gas.FillPressure(0.0111, free, zeroCelsius+10); math.Abs(p-232e5) > 10 {
+				t.Errorf("expected 232 bar for %s, got %v", gas.Name(), p)
+			}
+		}
+	})
+
+	t.Run("tanks should use their volume and mix", func(t *testing.T) {
+		tank := &TankData{TankVolume: ptr(0.012)}
+		ean := GasFractions{O2: 0.32, N2: 0.68}.Mix("ean32")
+		if got, want := tank.FreeGas(&ean, 200e5, 0), ean.Fractions().FreeGasVolume(0.012, 200e5, 0); got != want {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+		if free := (&Tank{}).FreeGas(nil, 200e5, 0); free != 0 {
+			t.Errorf("expected no gas without a volume, got %v", free)
+		}
+	})
+}
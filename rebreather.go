@@ -0,0 +1,320 @@
+package uddf
+
+import (
+	"math"
+	"slices"
+)
+
+// LoopOptions tune AnalyzeLoop. Zero values select the default given in
+// brackets.
+type LoopOptions struct {
+	VotingThreshold            float64     // deviation in bar from the median reading at which a sensor is voted out [0.2]
+	DriftThreshold             float64     // deviation in bar from the voted value that counts as drift [0.1]
+	DriftDuration              float64     // seconds a sensor must keep deviating to be flagged as drifting [60]
+	MinimumPo2                 float64     // bar, lower oxygen partial pressures are hypoxic [0.16]
+	MaximumPo2                 float64     // bar, higher oxygen partial pressures are hyperoxic [1.6]
+	LoopVolume                 float64     // volume of the breathing loop in m^3 [0.006]
+	MetabolicO2                float64     // oxygen metabolised in m^3/s at surface pressure [1 l/min]
+	BreathingConsumptionVolume float64     // open circuit surface consumption in m^3/s [20 l/min]
+	Environment                Environment // [that of the dive]
+}
+
+func (o LoopOptions) withDefaults() LoopOptions {
+	setDefault(&o.VotingThreshold, 0.2)
+	setDefault(&o.DriftThreshold, 0.1)
+	setDefault(&o.DriftDuration, 60)
+	setDefault(&o.MinimumPo2, minimumPo2)
+	setDefault(&o.MaximumPo2, 1.6)
+	setDefault(&o.LoopVolume, 0.006)
+	setDefault(&o.MetabolicO2, 0.001/60)
+	setDefault(&o.BreathingConsumptionVolume, 0.02/60)
+	return o
+}
+
+// LoopAnalysis is the result of AnalyzeLoop. Partial pressures are given in
+// bar, gas volumes in m^3 at surface pressure.
+type LoopAnalysis struct {
+	Samples     []LoopSample
+	Sensors     []SensorStatus
+	Setpoints   []SetpointSwitch
+	Excursions  []Po2Excursion
+	Segments    []LoopSegment
+	Oxygen      float64 // oxygen added to the loop
+	Diluent     float64 // diluent added to the loop
+	OpenCircuit float64 // gas breathed on open circuit, including bailout
+	Bailout     float64 // gas breathed on open circuit after leaving the loop
+}
+
+// LoopSample is the state of the loop at a waypoint.
+type LoopSample struct {
+	DiveTime float64
+	Depth    float64
+	Mode     string  // dive mode in effect, e.g. "closedcircuit"
+	Setpoint float64 // zero if none was set
+	// Po2 is the inspired oxygen partial pressure: the voted sensor reading
+	// or the calculated value on the loop, that of the mix breathed on open
+	// circuit. It is zero if unknown.
+	Po2      float64
+	VotedOut []string // IDs of the sensors disagreeing with the others
+}
+
+// SensorStatus summarises the readings of one oxygen sensor.
+type SensorStatus struct {
+	Ref           string
+	Part          *EquipmentPart // nil if the sensor is not in the inventory
+	Readings      int
+	VotedOut      int     // readings the sensor was voted out
+	MeanDeviation float64 // mean absolute deviation from the voted value
+	MaxDeviation  float64
+	// Drifting is set if the sensor deviated from the voted value by more
+	// than DriftThreshold for DriftDuration, starting at DriftStart.
+	Drifting   bool
+	DriftStart float64
+}
+
+// SetpointSwitch is a change of the setpoint during the dive.
+type SetpointSwitch struct {
+	DiveTime float64
+	Depth    float64
+	From     float64
+	To       float64
+	SetBy    string // "user" or "computer"
+}
+
+// Po2Excursion is a period with the oxygen partial pressure outside the
+// limits.
+type Po2Excursion struct {
+	Kind    string // "hypoxic" or "hyperoxic"
+	Start   float64
+	End     float64
+	Extreme float64 // lowest or highest partial pressure reached
+}
+
+// LoopSegment is a part of the dive in one dive mode.
+type LoopSegment struct {
+	Mode        string
+	Bailout     bool // open circuit after having been on the loop
+	Start       float64
+	End         float64
+	Mix         *Mix // open circuit mix breathed at the start, nil if unknown
+	Oxygen      float64
+	Diluent     float64
+	OpenCircuit float64
+}
+
+// AnalyzeLoop evaluates the rebreather data recorded in the samples of d.
+// The dive mode is taken from DiveMode and defaults to closed circuit if any
+// oxygen partial pressure was recorded. With three or more sensors, readings
+// deviating from the median by more than VotingThreshold are voted out and
+// the rest are averaged; with fewer, all readings are averaged. Gas usage is
+// estimated: the loop needs MetabolicO2 oxygen and LoopVolume diluent for
+// every increase of the ambient pressure, open circuit BreathingConsumptionVolume
+// at the ambient pressure.
+func (u *UDDF) AnalyzeLoop(d *Dive, opts LoopOptions) *LoopAnalysis {
+	opts = opts.withDefaults()
+	if opts.Environment == (Environment{}) {
+		opts.Environment = u.Environment(d)
+	}
+	a := &LoopAnalysis{}
+	if d.Samples == nil || len(d.Samples.Waypoints) == 0 {
+		return a
+	}
+	waypoints := d.Samples.Waypoints
+
+	mode := "opencircuit"
+	for _, w := range waypoints {
+		if w.DiveMode != nil {
+			mode = w.DiveMode.Type
+			break
+		}
+		if len(w.MeasuredPo2s) > 0 || len(w.SetPo2s) > 0 || w.CalculatedPo2 != nil {
+			mode = "closedcircuit"
+			break
+		}
+	}
+
+	inventory := u.Inventory()
+	sensors := map[string]*SensorStatus{}
+	var order []string
+	driftSince := map[string]float64{}
+	deviations := map[string]float64{}
+	var mix *Mix
+	var setpoint float64
+	var excursion *Po2Excursion
+	var segment *LoopSegment
+	onLoop := false
+
+	for i, w := range waypoints {
+		if i > 0 {
+			// the interval since the previous waypoint was spent in the
+			// mode in effect there
+			prev := waypoints[i-1]
+			dt := w.DiveTime - prev.DiveTime
+			p0 := opts.Environment.Pressure(prev.Depth)
+			p1 := opts.Environment.Pressure(w.Depth)
+			switch {
+			case isLoop(mode):
+				segment.Oxygen += opts.MetabolicO2 * dt
+				if p1 > p0 {
+					segment.Diluent += opts.LoopVolume * (p1 - p0) / seaLevelPressure
+				}
+			case mode == "opencircuit":
+				segment.OpenCircuit += opts.BreathingConsumptionVolume * (p0 + p1) / 2 / seaLevelPressure * dt
+			}
+			segment.End = w.DiveTime
+		}
+
+		if w.DiveMode != nil {
+			mode = w.DiveMode.Type
+		}
+		if w.SwitchMix != nil {
+			mix = u.mix(w.SwitchMix.Ref)
+		}
+		if segment == nil || segment.Mode != mode {
+			a.Segments = append(a.Segments, LoopSegment{Mode: mode, Bailout: mode == "opencircuit" && onLoop, Start: w.DiveTime, End: w.DiveTime})
+			segment = &a.Segments[len(a.Segments)-1]
+			if mode == "opencircuit" {
+				segment.Mix = mix
+			}
+		}
+		loop := isLoop(mode)
+		onLoop = onLoop || loop
+
+		if n := len(w.SetPo2s); n > 0 {
+			set := w.SetPo2s[n-1]
+			value := set.Value / pascalPerBar
+			if setpoint > 0 && value != setpoint {
+				a.Setpoints = append(a.Setpoints, SetpointSwitch{DiveTime: w.DiveTime, Depth: w.Depth, From: setpoint, To: value, SetBy: set.SetBy})
+			}
+			setpoint = value
+		}
+
+		sample := LoopSample{DiveTime: w.DiveTime, Depth: w.Depth, Mode: mode, Setpoint: setpoint}
+		readings := make([]float64, len(w.MeasuredPo2s))
+		for j, m := range w.MeasuredPo2s {
+			readings[j] = m.Value / pascalPerBar
+		}
+		voted, out := votePo2(readings, opts.VotingThreshold)
+		for j, m := range w.MeasuredPo2s {
+			s := sensors[m.Ref]
+			if s == nil {
+				s = &SensorStatus{Ref: m.Ref}
+				if item := inventory.Find(m.Ref); item != nil {
+					s.Part = item.Part
+				}
+				sensors[m.Ref] = s
+				order = append(order, m.Ref)
+			}
+			s.Readings++
+			if out[j] {
+				s.VotedOut++
+				sample.VotedOut = append(sample.VotedOut, m.Ref)
+			}
+			if len(readings) < 2 {
+				continue
+			}
+			dev := math.Abs(readings[j] - voted)
+			deviations[m.Ref] += dev
+			s.MaxDeviation = math.Max(s.MaxDeviation, dev)
+			if dev <= opts.DriftThreshold {
+				delete(driftSince, m.Ref)
+				continue
+			}
+			since, ok := driftSince[m.Ref]
+			if !ok {
+				since = w.DiveTime
+				driftSince[m.Ref] = since
+			}
+			if !s.Drifting && w.DiveTime-since >= opts.DriftDuration {
+				s.Drifting, s.DriftStart = true, since
+			}
+		}
+
+		switch {
+		case loop && len(readings) > 0:
+			sample.Po2 = voted
+		case w.CalculatedPo2 != nil:
+			sample.Po2 = *w.CalculatedPo2 / pascalPerBar
+		case mode == "opencircuit" && mix != nil:
+			sample.Po2 = mix.Fractions().O2 * opts.Environment.Pressure(w.Depth)
+		}
+		a.Samples = append(a.Samples, sample)
+
+		kind := ""
+		switch {
+		case sample.Po2 <= 0:
+		case sample.Po2 < opts.MinimumPo2:
+			kind = "hypoxic"
+		case sample.Po2 > opts.MaximumPo2:
+			kind = "hyperoxic"
+		}
+		if excursion != nil && excursion.Kind != kind {
+			excursion = nil
+		}
+		if kind == "" {
+			continue
+		}
+		if excursion == nil {
+			a.Excursions = append(a.Excursions, Po2Excursion{Kind: kind, Start: w.DiveTime, Extreme: sample.Po2})
+			excursion = &a.Excursions[len(a.Excursions)-1]
+		}
+		excursion.End = w.DiveTime
+		if kind == "hypoxic" {
+			excursion.Extreme = math.Min(excursion.Extreme, sample.Po2)
+		} else {
+			excursion.Extreme = math.Max(excursion.Extreme, sample.Po2)
+		}
+	}
+
+	for _, ref := range order {
+		s := sensors[ref]
+		s.MeanDeviation = deviations[ref] / float64(s.Readings)
+		a.Sensors = append(a.Sensors, *s)
+	}
+	for _, s := range a.Segments {
+		a.Oxygen += s.Oxygen
+		a.Diluent += s.Diluent
+		a.OpenCircuit += s.OpenCircuit
+		if s.Bailout {
+			a.Bailout += s.OpenCircuit
+		}
+	}
+	return a
+}
+
+// isLoop tells whether mode is a rebreather mode.
+func isLoop(mode string) bool {
+	return mode == "closedcircuit" || mode == "semiclosedcircuit"
+}
+
+// votePo2 returns the voted value of the sensor readings and which of them
+// were voted out. With three or more readings, those deviating from the
+// median by more than threshold are left out of the average.
+func votePo2(readings []float64, threshold float64) (float64, []bool) {
+	out := make([]bool, len(readings))
+	if len(readings) == 0 {
+		return 0, out
+	}
+	var median float64
+	if len(readings) >= 3 {
+		sorted := slices.Sorted(slices.Values(readings))
+		median = sorted[len(sorted)/2]
+		if len(sorted)%2 == 0 {
+			median = (median + sorted[len(sorted)/2-1]) / 2
+		}
+	}
+	var sum float64
+	var n int
+	for i, r := range readings {
+		if len(readings) >= 3 && math.Abs(r-median) > threshold {
+			out[i] = true
+			continue
+		}
+		sum += r
+		n++
+	}
+	if n == 0 {
+		return median, out
+	}
+	return sum / float64(n), out
+}
@@ -0,0 +1,109 @@
+package uddf
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestAnalyzeLoop(t *testing.T) {
+	u := buildDocument(t, func(b *Builder) {
+		b.AddMix("Air", 0.21, 0).
+			AddDive(func(d *DiveBuilder) {
+				d.At(time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC))
+			})
+	})
+	rebreather := Rebreather{O2Sensors: []EquipmentPart{{Id: "cell-1"}, {Id: "cell-2"}, {Id: "cell-3"}}}
+	rebreather.Id = "ccr"
+	u.Diver.Owner.Equipment = &Equipment{}
+	u.Diver.Owner.Equipment.Rebreathers = []Rebreather{rebreather}
+
+	cells := func(po2s ...float64) []MeasuredPo2 {
+		var m []MeasuredPo2
+		for i, p := range po2s {
+			m = append(m, MeasuredPo2{Ref: []string{"cell-1", "cell-2", "cell-3"}[i], Value: p * 1e5})
+		}
+		return m
+	}
+	d := &u.ProfileData.RepetitionGroup[0].Dives[0]
+	d.Samples = &Samples{Waypoints: []Waypoint{
+		{DiveTime: 0, Depth: 0, DiveMode: &DiveMode{Type: "closedcircuit"}, MeasuredPo2s: cells(0.15, 0.15, 0.15)},
+		{DiveTime: 60, Depth: 10, SetPo2s: []SetPo2{{SetBy: "user", Value: 0.7e5}}, MeasuredPo2s: cells(0.70, 0.70, 0.70)},
+		{DiveTime: 120, Depth: 30, SetPo2s: []SetPo2{{SetBy: "computer", Value: 1.3e5}}, MeasuredPo2s: cells(1.30, 1.29, 1.00)},
+		{DiveTime: 300, Depth: 30, MeasuredPo2s: cells(1.30, 1.31, 1.05)},
+		{DiveTime: 600, Depth: 30, MeasuredPo2s: cells(1.70, 1.70, 1.65)},
+		{DiveTime: 660, Depth: 20, DiveMode: &DiveMode{Type: "opencircuit"}, SwitchMix: &SwitchMix{Ref: "mix-air"}},
+		{DiveTime: 900, Depth: 0},
+	}}
+	a := u.AnalyzeLoop(d, LoopOptions{})
+
+	near := func(a, b float64) bool { return math.Abs(a-b) < 1e-6 }
+
+	t.Run("should vote out the disagreeing sensor", func(t *testing.T) {
+		s := a.Samples[2]
+		if !near(s.Po2, 1.295) || len(s.VotedOut) != 1 || s.VotedOut[0] != "cell-3" {
+			t.Errorf("unexpected sample %+v", s)
+		}
+		if len(a.Sensors) != 3 || a.Sensors[2].VotedOut != 2 || a.Sensors[0].VotedOut != 0 {
+			t.Errorf("unexpected sensors %+v", a.Sensors)
+		}
+	})
+
+	t.Run("should flag drifting sensors", func(t *testing.T) {
+		cell := a.Sensors[2]
+		if !cell.Drifting || cell.DriftStart != 120 || cell.Part != &u.Diver.Owner.Equipment.Rebreathers[0].O2Sensors[2] {
+			t.Errorf("unexpected sensor %+v", cell)
+		}
+		if a.Sensors[0].Drifting || a.Sensors[1].Drifting {
+			t.Errorf("expected only cell-3 to drift, got %+v", a.Sensors)
+		}
+	})
+
+	t.Run("should detect setpoint switches", func(t *testing.T) {
+		if len(a.Setpoints) != 1 || a.Setpoints[0] != (SetpointSwitch{DiveTime: 120, Depth: 30, From: 0.7, To: 1.3, SetBy: "computer"}) {
+			t.Errorf("unexpected setpoint switches %+v", a.Setpoints)
+		}
+		if a.Samples[3].Setpoint != 1.3 || a.Samples[0].Setpoint != 0 {
+			t.Errorf("unexpected setpoints %v and %v", a.Samples[0].Setpoint, a.Samples[3].Setpoint)
+		}
+	})
+
+	t.Run("should detect excursions", func(t *testing.T) {
+		if len(a.Excursions) != 2 {
+			t.Fatalf("expected 2 excursions, got %+v", a.Excursions)
+		}
+		if e := a.Excursions[0]; e.Kind != "hypoxic" || e.Start != 0 || e.End != 0 || !near(e.Extreme, 0.15) {
+			t.Errorf("unexpected excursion %+v", e)
+		}
+		if e := a.Excursions[1]; e.Kind != "hyperoxic" || e.Start != 600 || !near(e.Extreme, 5.05/3) {
+			t.Errorf("unexpected excursion %+v", e)
+		}
+	})
+
+	t.Run("should estimate gas usage per segment", func(t *testing.T) {
+		if len(a.Segments) != 2 {
+			t.Fatalf("expected 2 segments, got %+v", a.Segments)
+		}
+		loop, bailout := a.Segments[0], a.Segments[1]
+		if loop.Mode != "closedcircuit" || loop.Bailout || loop.End != 660 {
+			t.Errorf("unexpected loop segment %+v", loop)
+		}
+		if !bailout.Bailout || bailout.Start != 660 || bailout.Mix == nil || bailout.Mix.ID != "mix-air" {
+			t.Errorf("unexpected bailout segment %+v", bailout)
+		}
+		if !near(a.Oxygen, 0.011) {
+			t.Errorf("expected 11 l of oxygen, got %v", a.Oxygen)
+		}
+		diluent := 0.006 * (Environment{}.Pressure(30) - seaLevelPressure) / seaLevelPressure
+		if !near(a.Diluent, diluent) {
+			t.Errorf("expected %v m^3 of diluent, got %v", diluent, a.Diluent)
+		}
+		oc := 0.02 / 60 * (Environment{}.Pressure(20) + seaLevelPressure) / 2 / seaLevelPressure * 240
+		if !near(a.Bailout, oc) || a.OpenCircuit != a.Bailout {
+			t.Errorf("expected %v m^3 of bailout gas, got %v", oc, a.Bailout)
+		}
+		if po2 := a.Samples[5].Po2; !near(po2, 0.21*Environment{}.Pressure(20)) {
+			t.Errorf("unexpected open circuit ppO2 %v", po2)
+		}
+	})
+}
@@ -0,0 +1,644 @@
+package uddf
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Overlay selects additional information drawn on top of a depth profile.
+type Overlay int
+
+const (
+	OverlayDecoStops Overlay = 1 << iota
+	OverlayMixSwitches
+	OverlayAlarms
+	OverlayTemperature
+	OverlayTankPressure
+
+	OverlayAll = OverlayDecoStops | OverlayMixSwitches | OverlayAlarms | OverlayTemperature | OverlayTankPressure
+)
+
+// ChartOptions configure the rendering of a Chart. Zero values select the
+// defaults.
+type ChartOptions struct {
+	// Width and Height are in pixels for SVG and PNG [800x400] and in
+	// characters for text [72x16].
+	Width, Height int
+	// Hide lists the overlays to leave out.
+	Hide Overlay
+	// ASCII restricts text output to ASCII characters.
+	ASCII bool
+}
+
+// Chart is the profile of a dive prepared for rendering. Depths are in
+// metres and times in seconds.
+type Chart struct {
+	Title     string
+	Waypoints []Waypoint
+	// MixNames maps mix IDs to the names used to label switches.
+	MixNames map[string]string
+}
+
+// Chart prepares the profile of d for rendering. The title and the mix
+// names are looked up in u.
+func (u *UDDF) Chart(d *Dive) *Chart {
+	c := &Chart{MixNames: map[string]string{}}
+	if d.Samples != nil {
+		c.Waypoints = d.Samples.Waypoints
+	}
+	if u.GasDefinitions != nil {
+		for _, m := range u.GasDefinitions.Mixes {
+			c.MixNames[m.ID] = m.Name
+		}
+	}
+
+	title := []string{d.ID}
+	if t := d.InformationBeforeDive.DateTime; !time.Time(t).IsZero() {
+		title = append(title, t.display())
+	}
+	if u.DiveSite != nil {
+		for _, l := range d.InformationBeforeDive.Links {
+			for _, s := range u.DiveSite.Sites {
+				if s.ID == l.Ref {
+					title = append(title, s.Name)
+				}
+			}
+		}
+	}
+	c.Title = strings.Join(title, " ")
+	return c
+}
+
+type chartPoint struct {
+	t, v float64
+}
+
+// chartEvent is a mix switch or an alarm.
+type chartEvent struct {
+	t, depth float64
+	alarm    bool
+	label    string
+}
+
+// chartSeries is a secondary line drawn on its own scale.
+type chartSeries struct {
+	name, unit string
+	color      color.RGBA
+	points     []chartPoint
+	lo, hi     float64
+}
+
+var (
+	chartWater     = color.RGBA{0xcf, 0xe3, 0xf5, 0xff}
+	chartDepth     = color.RGBA{0x1f, 0x5f, 0xa8, 0xff}
+	chartGrid      = color.RGBA{0xdd, 0xdd, 0xdd, 0xff}
+	chartAxis      = color.RGBA{0x44, 0x44, 0x44, 0xff}
+	chartCeiling   = color.RGBA{0xd6, 0x27, 0x28, 0xff}
+	chartSwitch    = color.RGBA{0x2c, 0xa0, 0x2c, 0xff}
+	chartAlarm     = color.RGBA{0xff, 0x7f, 0x0e, 0xff}
+	chartTemp      = color.RGBA{0x94, 0x67, 0xbd, 0xff}
+	chartPressures = []color.RGBA{{0x7f, 0x7f, 0x7f, 0xff}, {0x8c, 0x56, 0x4b, 0xff}, {0x17, 0xbe, 0xcf, 0xff}, {0xbc, 0xbd, 0x22, 0xff}}
+)
+
+func (o ChartOptions) shows(overlay Overlay) bool {
+	return o.Hide&overlay == 0
+}
+
+func (o ChartOptions) size(width, height int) (int, int) {
+	if o.Width > 0 {
+		width = o.Width
+	}
+	if o.Height > 0 {
+		height = o.Height
+	}
+	return width, height
+}
+
+// duration returns the time of the last waypoint, at least one second.
+func (c *Chart) duration() float64 {
+	if len(c.Waypoints) == 0 {
+		return 1
+	}
+	return math.Max(c.Waypoints[len(c.Waypoints)-1].DiveTime, 1)
+}
+
+func (c *Chart) maxDepth() float64 {
+	depth := 0.0
+	for _, w := range c.Waypoints {
+		depth = math.Max(depth, w.Depth)
+	}
+	return depth
+}
+
+// depthAt interpolates the depth at time t.
+func (c *Chart) depthAt(t float64) float64 {
+	ws := c.Waypoints
+	i := sort.Search(len(ws), func(i int) bool { return ws[i].DiveTime >= t })
+	switch {
+	case len(ws) == 0:
+		return 0
+	case i == 0:
+		return ws[0].Depth
+	case i == len(ws):
+		return ws[len(ws)-1].Depth
+	}
+	a, b := ws[i-1], ws[i]
+	if b.DiveTime == a.DiveTime {
+		return b.Depth
+	}
+	return a.Depth + (b.Depth-a.Depth)*(t-a.DiveTime)/(b.DiveTime-a.DiveTime)
+}
+
+// columnDepth returns the greatest depth between t0 and t1.
+func (c *Chart) columnDepth(t0, t1 float64) float64 {
+	depth := math.Max(c.depthAt(t0), c.depthAt(t1))
+	for _, w := range c.Waypoints {
+		if w.DiveTime > t0 && w.DiveTime < t1 {
+			depth = math.Max(depth, w.Depth)
+		}
+	}
+	return depth
+}
+
+// ceilings returns the deepest mandatory stop of every waypoint, zero where
+// there is none.
+func (c *Chart) ceilings() []chartPoint {
+	var points []chartPoint
+	found := false
+	for _, w := range c.Waypoints {
+		ceiling := 0.0
+		for _, s := range w.DecoStops {
+			if s.Kind == "mandatory" {
+				ceiling = math.Max(ceiling, s.DecoDepth)
+			}
+		}
+		found = found || ceiling > 0
+		points = append(points, chartPoint{w.DiveTime, ceiling})
+	}
+	if !found {
+		return nil
+	}
+	return points
+}
+
+func (c *Chart) events(opts ChartOptions) []chartEvent {
+	var events []chartEvent
+	for _, w := range c.Waypoints {
+		if w.SwitchMix != nil && opts.shows(OverlayMixSwitches) {
+			name := c.MixNames[w.SwitchMix.Ref]
+			if name == "" {
+				name = w.SwitchMix.Ref
+			}
+			events = append(events, chartEvent{t: w.DiveTime, depth: w.Depth, label: name})
+		}
+		if opts.shows(OverlayAlarms) {
+			for _, a := range w.Alarms {
+				events = append(events, chartEvent{t: w.DiveTime, depth: w.Depth, alarm: true, label: a.Value})
+			}
+		}
+	}
+	return events
+}
+
+// series returns the temperature in °C and the pressure of every tank in
+// bar, each scaled to its own range.
+func (c *Chart) series(opts ChartOptions) []chartSeries {
+	var result []chartSeries
+	if opts.shows(OverlayTemperature) {
+		s := chartSeries{name: "Temperature", unit: "°C", color: chartTemp}
+		for _, w := range c.Waypoints {
+			if w.Temperature > 0 {
+				s.points = append(s.points, chartPoint{w.DiveTime, celsius(w.Temperature)})
+			}
+		}
+		if len(s.points) > 0 {
+			s.lo, s.hi = seriesRange(s.points)
+			s.lo, s.hi = s.lo-1, s.hi+1
+			result = append(result, s)
+		}
+	}
+
+	if opts.shows(OverlayTankPressure) {
+		var refs []string
+		tanks := map[string][]chartPoint{}
+		for _, w := range c.Waypoints {
+			for _, p := range w.TankPressures {
+				ref := ""
+				if p.Ref != nil {
+					ref = *p.Ref
+				}
+				if _, ok := tanks[ref]; !ok {
+					refs = append(refs, ref)
+				}
+				tanks[ref] = append(tanks[ref], chartPoint{w.DiveTime, p.Value / pascalPerBar})
+			}
+		}
+		for i, ref := range refs {
+			name := "Tank pressure"
+			if ref != "" {
+				name = "Tank " + ref
+			}
+			s := chartSeries{name: name, unit: "bar", color: chartPressures[i%len(chartPressures)], points: tanks[ref]}
+			_, s.hi = seriesRange(s.points)
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+func seriesRange(points []chartPoint) (lo, hi float64) {
+	lo, hi = math.Inf(1), math.Inf(-1)
+	for _, p := range points {
+		lo, hi = math.Min(lo, p.v), math.Max(hi, p.v)
+	}
+	if lo == hi {
+		hi = lo + 1
+	}
+	return lo, hi
+}
+
+// niceStep returns a step of 1, 2 or 5 times a power of ten that divides
+// max into at most n intervals.
+func niceStep(max float64, n int) float64 {
+	if max <= 0 {
+		return 1
+	}
+	magnitude := math.Pow(10, math.Floor(math.Log10(max/float64(n))))
+	for _, f := range []float64{1, 2, 5, 10} {
+		if max/(f*magnitude) <= float64(n) {
+			return f * magnitude
+		}
+	}
+	return 10 * magnitude
+}
+
+// chartFrame maps times and depths to the plot area of a graphic.
+type chartFrame struct {
+	width, height            float64
+	left, right, top, bottom float64
+	duration, depth          float64
+	timeStep, depthStep      float64
+}
+
+func (c *Chart) frame(width, height int) chartFrame {
+	f := chartFrame{
+		width: float64(width), height: float64(height),
+		left: 50, right: 20, top: 30, bottom: 35,
+		duration: c.duration(),
+	}
+	f.timeStep = niceStep(f.duration/60, 10) * 60
+	f.depthStep = niceStep(math.Max(c.maxDepth(), 1), 8)
+	f.depth = math.Max(math.Ceil(c.maxDepth()/f.depthStep), 1) * f.depthStep
+	return f
+}
+
+func (f chartFrame) x(t float64) float64 {
+	return f.left + t/f.duration*(f.width-f.left-f.right)
+}
+
+func (f chartFrame) y(depth float64) float64 {
+	return f.top + depth/f.depth*(f.height-f.top-f.bottom)
+}
+
+// scaled maps a value of s to a y coordinate, the highest value at the top.
+func (f chartFrame) scaled(s chartSeries, v float64) float64 {
+	return f.top + (1-(v-s.lo)/(s.hi-s.lo))*(f.height-f.top-f.bottom)
+}
+
+// WriteSVG renders the chart as an SVG image.
+func (c *Chart) WriteSVG(w io.Writer, opts ChartOptions) error {
+	width, height := opts.size(800, 400)
+	f := c.frame(width, height)
+	var b strings.Builder
+
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="11">`+"\n", width, height, width, height)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="white"/>`+"\n", width, height)
+	if c.Title != "" {
+		fmt.Fprintf(&b, `<text x="%g" y="18" font-size="13" font-weight="bold">%s</text>`+"\n", f.left, svgEscape(c.Title))
+	}
+
+	for d := 0.0; d <= f.depth; d += f.depthStep {
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="%s"/>`+"\n", f.left, f.y(d), f.width-f.right, f.y(d), svgColor(chartGrid))
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" text-anchor="end">%g m</text>`+"\n", f.left-5, f.y(d)+4, d)
+	}
+	for t := 0.0; t <= f.duration; t += f.timeStep {
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="%s"/>`+"\n", f.x(t), f.top, f.x(t), f.y(f.depth), svgColor(chartGrid))
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" text-anchor="middle">%g</text>`+"\n", f.x(t), f.y(f.depth)+15, t/60)
+	}
+	fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" text-anchor="end">min</text>`+"\n", f.width-f.right, f.height-5)
+
+	if len(c.Waypoints) > 0 {
+		points := make([]string, len(c.Waypoints))
+		for i, wp := range c.Waypoints {
+			points[i] = fmt.Sprintf("%.1f,%.1f", f.x(wp.DiveTime), f.y(wp.Depth))
+		}
+		first, last := c.Waypoints[0], c.Waypoints[len(c.Waypoints)-1]
+		fmt.Fprintf(&b, `<polygon class="water" points="%.1f,%.1f %s %.1f,%.1f" fill="%s"/>`+"\n", f.x(first.DiveTime), f.y(0), strings.Join(points, " "), f.x(last.DiveTime), f.y(0), svgColor(chartWater))
+		fmt.Fprintf(&b, `<polyline class="depth" points="%s" fill="none" stroke="%s" stroke-width="2"/>`+"\n", strings.Join(points, " "), svgColor(chartDepth))
+	}
+
+	if opts.shows(OverlayDecoStops) {
+		var segment []string
+		flush := func() {
+			if len(segment) > 1 {
+				fmt.Fprintf(&b, `<polyline class="ceiling" points="%s" fill="none" stroke="%s" stroke-width="1.5" stroke-dasharray="4 2"/>`+"\n", strings.Join(segment, " "), svgColor(chartCeiling))
+			}
+			segment = nil
+		}
+		for _, p := range c.ceilings() {
+			if p.v == 0 {
+				flush()
+				continue
+			}
+			segment = append(segment, fmt.Sprintf("%.1f,%.1f", f.x(p.t), f.y(p.v)))
+		}
+		flush()
+	}
+
+	series := c.series(opts)
+	for _, s := range series {
+		points := make([]string, len(s.points))
+		for i, p := range s.points {
+			points[i] = fmt.Sprintf("%.1f,%.1f", f.x(p.t), f.scaled(s, p.v))
+		}
+		last := s.points[len(s.points)-1]
+		fmt.Fprintf(&b, `<polyline class="series" points="%s" fill="none" stroke="%s" stroke-width="1"><title>%s</title></polyline>`+"\n", strings.Join(points, " "), svgColor(s.color), svgEscape(s.name))
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" fill="%s" text-anchor="end">%.1f %s</text>`+"\n", f.x(last.t)-3, f.scaled(s, last.v)-3, svgColor(s.color), last.v, s.unit)
+	}
+
+	for _, e := range c.events(opts) {
+		if e.alarm {
+			fmt.Fprintf(&b, `<circle class="alarm" cx="%.1f" cy="%.1f" r="4" fill="%s"><title>%s</title></circle>`+"\n", f.x(e.t), f.y(e.depth), svgColor(chartAlarm), svgEscape(e.label))
+			continue
+		}
+		fmt.Fprintf(&b, `<line class="switch" x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="%s" stroke-dasharray="3 3"/>`+"\n", f.x(e.t), f.top, f.x(e.t), f.y(f.depth), svgColor(chartSwitch))
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" fill="%s">%s</text>`+"\n", f.x(e.t)+3, f.top+10, svgColor(chartSwitch), svgEscape(e.label))
+	}
+
+	x := f.width - f.right
+	for i := len(series) - 1; i >= 0; i-- {
+		s := series[i]
+		fmt.Fprintf(&b, `<text x="%.1f" y="18" fill="%s" text-anchor="end">%s</text>`+"\n", x, svgColor(s.color), svgEscape(s.name))
+		x -= float64(len(s.name))*6 + 12
+	}
+
+	b.WriteString("</svg>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func svgColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+var svgEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+
+func svgEscape(s string) string {
+	return svgEscaper.Replace(s)
+}
+
+// WritePNG renders the chart as a PNG image. Only the axis values are
+// labelled; titles and legends are left to the SVG output.
+func (c *Chart) WritePNG(w io.Writer, opts ChartOptions) error {
+	width, height := opts.size(800, 400)
+	f := c.frame(width, height)
+	r := raster{image.NewRGBA(image.Rect(0, 0, width, height))}
+	r.fill(0, 0, width, height, color.RGBA{0xff, 0xff, 0xff, 0xff})
+
+	for d := 0.0; d <= f.depth; d += f.depthStep {
+		r.line(f.left, f.y(d), f.width-f.right, f.y(d), chartGrid, 1, false)
+		r.digits(f.left-5, f.y(d)-5, fmt.Sprintf("%g", d), chartAxis, true)
+	}
+	for t := 0.0; t <= f.duration; t += f.timeStep {
+		r.line(f.x(t), f.top, f.x(t), f.y(f.depth), chartGrid, 1, false)
+		label := fmt.Sprintf("%g", t/60)
+		r.digits(f.x(t)+float64(len(label))*4-1, f.y(f.depth)+6, label, chartAxis, true)
+	}
+
+	for px := int(f.left); px <= int(f.width-f.right); px++ {
+		t0 := (float64(px) - f.left) / (f.width - f.left - f.right) * f.duration
+		if len(c.Waypoints) == 0 || t0 < c.Waypoints[0].DiveTime || t0 > c.Waypoints[len(c.Waypoints)-1].DiveTime {
+			continue
+		}
+		r.fill(px, int(f.y(0)), px+1, int(f.y(c.depthAt(t0))), chartWater)
+	}
+	for i := 1; i < len(c.Waypoints); i++ {
+		a, b := c.Waypoints[i-1], c.Waypoints[i]
+		r.line(f.x(a.DiveTime), f.y(a.Depth), f.x(b.DiveTime), f.y(b.Depth), chartDepth, 2, false)
+	}
+
+	if opts.shows(OverlayDecoStops) {
+		ceilings := c.ceilings()
+		for i := 1; i < len(ceilings); i++ {
+			a, b := ceilings[i-1], ceilings[i]
+			if a.v > 0 && b.v > 0 {
+				r.line(f.x(a.t), f.y(a.v), f.x(b.t), f.y(b.v), chartCeiling, 2, true)
+			}
+		}
+	}
+	for _, s := range c.series(opts) {
+		for i := 1; i < len(s.points); i++ {
+			a, b := s.points[i-1], s.points[i]
+			r.line(f.x(a.t), f.scaled(s, a.v), f.x(b.t), f.scaled(s, b.v), s.color, 1, false)
+		}
+	}
+	for _, e := range c.events(opts) {
+		if e.alarm {
+			r.circle(f.x(e.t), f.y(e.depth), 4, chartAlarm)
+		} else {
+			r.line(f.x(e.t), f.top, f.x(e.t), f.y(f.depth), chartSwitch, 1, true)
+		}
+	}
+
+	return png.Encode(w, r.img)
+}
+
+// raster draws lines, shapes and digits on an image.
+type raster struct {
+	img *image.RGBA
+}
+
+func (r raster) fill(x0, y0, x1, y1 int, c color.RGBA) {
+	for y := y0; y < y1; y++ {
+		for x := x0; x < x1; x++ {
+			r.img.SetRGBA(x, y, c)
+		}
+	}
+}
+
+// line draws a line of the given thickness, every other few pixels left
+// out if dashed.
+func (r raster) line(x0, y0, x1, y1 float64, c color.RGBA, thickness int, dashed bool) {
+	steps := int(math.Max(math.Abs(x1-x0), math.Abs(y1-y0))) + 1
+	for i := 0; i <= steps; i++ {
+		if dashed && i%6 >= 4 {
+			continue
+		}
+		x := int(math.Round(x0 + (x1-x0)*float64(i)/float64(steps)))
+		y := int(math.Round(y0 + (y1-y0)*float64(i)/float64(steps)))
+		r.fill(x-thickness/2, y-thickness/2, x-thickness/2+thickness, y-thickness/2+thickness, c)
+	}
+}
+
+func (r raster) circle(cx, cy, radius float64, c color.RGBA) {
+	for y := int(cy - radius); y <= int(cy+radius); y++ {
+		for x := int(cx - radius); x <= int(cx+radius); x++ {
+			if math.Hypot(float64(x)-cx, float64(y)-cy) <= radius {
+				r.img.SetRGBA(x, y, c)
+			}
+		}
+	}
+}
+
+// glyphs are 3x5 pixel bitmaps of the characters used for axis labels, one
+// row of three bits per line.
+var glyphs = map[rune][5]uint8{
+	'0': {7, 5, 5, 5, 7}, '1': {2, 6, 2, 2, 7}, '2': {7, 1, 7, 4, 7}, '3': {7, 1, 7, 1, 7},
+	'4': {5, 5, 7, 1, 1}, '5': {7, 4, 7, 1, 7}, '6': {7, 4, 7, 5, 7}, '7': {7, 1, 1, 1, 1},
+	'8': {7, 5, 7, 5, 7}, '9': {7, 5, 7, 1, 7}, '.': {0, 0, 0, 0, 2}, '-': {0, 0, 7, 0, 0},
+}
+
+// digits draws s at twice the glyph size with its top at y, ending at x if
+// alignEnd is set and starting there otherwise.
+func (r raster) digits(x, y float64, s string, c color.RGBA, alignEnd bool) {
+	const scale, advance = 2, 8
+	if alignEnd {
+		x -= float64(len(s)*advance - scale)
+	}
+	for i, ch := range s {
+		glyph := glyphs[ch]
+		for row, bits := range glyph {
+			for col := 0; col < 3; col++ {
+				if bits&(4>>col) != 0 {
+					px, py := int(x)+i*advance+col*scale, int(y)+row*scale
+					r.fill(px, py, px+scale, py+scale, c)
+				}
+			}
+		}
+	}
+}
+
+var sparkBlocks = []rune(" ▁▂▃▄▅▆▇█")
+
+// Sparkline renders the depth profile as a single line of width block
+// characters, taller blocks being deeper.
+func (c *Chart) Sparkline(width int) string {
+	if width <= 0 {
+		width = 72
+	}
+	depth := c.maxDepth()
+	var b strings.Builder
+	step := c.duration() / float64(width)
+	for i := 0; i < width; i++ {
+		level := 0
+		if depth > 0 {
+			d := c.columnDepth(float64(i)*step, float64(i+1)*step)
+			level = int(math.Ceil(d / depth * float64(len(sparkBlocks)-1)))
+		}
+		// readings above the surface show as the surface
+		level = min(max(level, 0), len(sparkBlocks)-1)
+		b.WriteRune(sparkBlocks[level])
+	}
+	return b.String()
+}
+
+// WriteText renders the chart for a terminal: the depth profile filled from
+// the surface, mandatory stops marked with '=', mix switches with '^' and
+// alarms with '!' below the time axis, followed by a legend.
+func (c *Chart) WriteText(w io.Writer, opts ChartOptions) error {
+	width, height := opts.size(72, 16)
+	full, half := "█", "▀"
+	if opts.ASCII {
+		full, half = "#", "'"
+	}
+
+	duration, depth := c.duration(), c.maxDepth()
+	if depth == 0 {
+		depth = 1
+	}
+	step := duration / float64(width)
+	rowDepth := depth / float64(height)
+
+	columns := make([]float64, width)
+	ceilings := make([]float64, width)
+	ceilingPoints := c.ceilings()
+	for i := range columns {
+		columns[i] = c.columnDepth(float64(i)*step, float64(i+1)*step)
+		if !opts.shows(OverlayDecoStops) {
+			continue
+		}
+		for _, p := range ceilingPoints {
+			if p.t >= float64(i)*step && p.t < float64(i+1)*step {
+				ceilings[i] = math.Max(ceilings[i], p.v)
+			}
+		}
+	}
+
+	var b strings.Builder
+	if c.Title != "" {
+		fmt.Fprintf(&b, "%s\n", c.Title)
+	}
+	for row := 0; row < height; row++ {
+		top, bottom := float64(row)*rowDepth, float64(row+1)*rowDepth
+		switch row {
+		case 0:
+			fmt.Fprintf(&b, "%5.0f m |", 0.0)
+		case height - 1:
+			fmt.Fprintf(&b, "%5.0f m |", depth)
+		default:
+			b.WriteString("        |")
+		}
+		for i, d := range columns {
+			switch {
+			case ceilings[i] > top && ceilings[i] <= bottom:
+				b.WriteString("=")
+			case d >= top+rowDepth*0.75:
+				b.WriteString(full)
+			case d >= top+rowDepth*0.25:
+				b.WriteString(half)
+			default:
+				b.WriteString(" ")
+			}
+		}
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(&b, "        +%s\n", strings.Repeat("-", width))
+
+	events := c.events(opts)
+	if len(events) > 0 {
+		marks := []rune(strings.Repeat(" ", width))
+		for _, e := range events {
+			i := min(max(int(e.t/step), 0), width-1)
+			if e.alarm {
+				marks[i] = '!'
+			} else if marks[i] != '!' {
+				marks[i] = '^'
+			}
+		}
+		fmt.Fprintf(&b, "         %s\n", strings.TrimRight(string(marks), " "))
+	}
+	end := fmt.Sprintf("%.0f min", duration/60)
+	fmt.Fprintf(&b, "         0%s%s\n", strings.Repeat(" ", max(width-1-len(end), 1)), end)
+
+	for _, e := range events {
+		if e.alarm {
+			fmt.Fprintf(&b, "  ! %3.0f min %5.1f m  alarm: %s\n", e.t/60, e.depth, e.label)
+		} else {
+			fmt.Fprintf(&b, "  ^ %3.0f min %5.1f m  switch to %s\n", e.t/60, e.depth, e.label)
+		}
+	}
+	for _, s := range c.series(opts) {
+		first, last := s.points[0], s.points[len(s.points)-1]
+		fmt.Fprintf(&b, "  %s: %.1f to %.1f %s\n", s.name, first.v, last.v, s.unit)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
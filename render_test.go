@@ -0,0 +1,140 @@
+package uddf
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+// chartDocument builds a dive with a mix switch, an alarm, a mandatory stop,
+// temperatures and tank pressures.
+func chartDocument(t *testing.T) (*UDDF, *Dive) {
+	t.Helper()
+	u := buildDocument(t, func(b *Builder) {
+		b.AddMix("Air", 0.21, 0).
+			AddMix("EAN50", 0.5, 0).
+			AddSite("Blue Hole", 17.3, -87.5).
+			AddDive(func(d *DiveBuilder) {
+				d.At(time.Date(2023, 8, 1, 9, 0, 0, 0, time.UTC)).Site("Blue Hole").Tank("Air", 0.012, 200e5, 60e5)
+				d.Sample(0, 0).Sample(5*time.Minute, 40).Sample(25*time.Minute, 40).
+					Sample(30*time.Minute, 21).Sample(35*time.Minute, 6).Sample(45*time.Minute, 0)
+			})
+	})
+
+	d := &u.ProfileData.RepetitionGroup[0].Dives[0]
+	ws := d.Samples.Waypoints
+	for i := range ws {
+		ws[i].Temperature = 299.15 - float64(i)*0.5
+		ws[i].TankPressures = []TankPressure{{Ref: ptr("tank-1"), Value: 200e5 - float64(i)*25e5}}
+	}
+	ws[2].DecoStops = []Decostop{{Kind: "mandatory", DecoDepth: 6, Duration: 300}}
+	ws[3].DecoStops = []Decostop{{Kind: "mandatory", DecoDepth: 3, Duration: 120}}
+	ws[3].SwitchMix = &SwitchMix{Ref: "mix-ean50"}
+	ws[4].Alarms = []Alarm{{Value: "ascent"}}
+	return u, d
+}
+
+func TestChart(t *testing.T) {
+	u, d := chartDocument(t)
+	c := u.Chart(d)
+
+	t.Run("should resolve the title and mix names", func(t *testing.T) {
+		if c.Title != "dive-1 2023-08-01 09:00 Blue Hole" {
+			t.Errorf("unexpected title %q", c.Title)
+		}
+		if c.MixNames["mix-ean50"] != "EAN50" {
+			t.Errorf("expected mix names, got %v", c.MixNames)
+		}
+	})
+
+	t.Run("SVG should contain the profile and the overlays", func(t *testing.T) {
+		var b bytes.Buffer
+		if err := c.WriteSVG(&b, ChartOptions{}); err != nil {
+			t.Fatalf("failed to render SVG: %v", err)
+		}
+		svg := b.String()
+		for _, want := range []string{`width="800" height="400"`, `class="depth"`, `class="ceiling"`, `class="switch"`, ">EAN50<", `class="alarm"`, "<title>ascent</title>", "<title>Temperature</title>", "<title>Tank tank-1</title>", ">45<", ">40 m<"} {
+			if !strings.Contains(svg, want) {
+				t.Errorf("expected SVG to contain %q", want)
+			}
+		}
+
+		b.Reset()
+		if err := c.WriteSVG(&b, ChartOptions{Hide: OverlayAll}); err != nil {
+			t.Fatalf("failed to render SVG: %v", err)
+		}
+		for _, unwanted := range []string{"ceiling", "switch", "alarm", "series"} {
+			if strings.Contains(b.String(), unwanted) {
+				t.Errorf("expected hidden overlay %q", unwanted)
+			}
+		}
+	})
+
+	t.Run("PNG should have the requested size", func(t *testing.T) {
+		var b bytes.Buffer
+		if err := c.WritePNG(&b, ChartOptions{Width: 320, Height: 200}); err != nil {
+			t.Fatalf("failed to render PNG: %v", err)
+		}
+		img, err := png.Decode(&b)
+		if err != nil {
+			t.Fatalf("failed to decode PNG: %v", err)
+		}
+		if size := img.Bounds().Size(); size.X != 320 || size.Y != 200 {
+			t.Errorf("expected 320x200, got %v", size)
+		}
+		if r, g, b, _ := img.At(160, 100).RGBA(); r == 0xffff && g == 0xffff && b == 0xffff {
+			t.Error("expected the water to be filled at the bottom of the dive")
+		}
+	})
+
+	t.Run("sparkline should have one block per column", func(t *testing.T) {
+		s := c.Sparkline(45)
+		if n := utf8.RuneCountInString(s); n != 45 {
+			t.Fatalf("expected 45 runes, got %d: %q", n, s)
+		}
+		if !strings.HasPrefix(s, "▂") || !strings.Contains(s, "█") {
+			t.Errorf("expected descent and bottom blocks, got %q", s)
+		}
+	})
+
+	t.Run("text should mark stops and events", func(t *testing.T) {
+		var b bytes.Buffer
+		if err := c.WriteText(&b, ChartOptions{Width: 45, Height: 8, ASCII: true}); err != nil {
+			t.Fatalf("failed to render text: %v", err)
+		}
+		text := b.String()
+		for _, want := range []string{"    0 m |", "   40 m |", "=", "^ ", "!", "switch to EAN50", "alarm: ascent", "Tank tank-1: 200.0 to 75.0 bar", "45 min"} {
+			if !strings.Contains(text, want) {
+				t.Errorf("expected text to contain %q, got:\n%s", want, text)
+			}
+		}
+		for _, line := range strings.Split(text, "\n") {
+			for _, r := range line {
+				if r > 127 && r != '°' {
+					t.Fatalf("expected ASCII output, got %q", line)
+				}
+			}
+		}
+	})
+
+	t.Run("negative depths and times should not break rendering", func(t *testing.T) {
+		odd := &Chart{Waypoints: []Waypoint{
+			{DiveTime: -30, Depth: -0.5, SwitchMix: &SwitchMix{Ref: "mix-air"}},
+			{DiveTime: 60, Depth: 10},
+			{DiveTime: 120, Depth: -1},
+		}}
+		if s := odd.Sparkline(10); utf8.RuneCountInString(s) != 10 {
+			t.Errorf("expected 10 runes, got %q", s)
+		}
+		var b bytes.Buffer
+		if err := odd.WriteText(&b, ChartOptions{Width: 10, Height: 4}); err != nil {
+			t.Fatalf("failed to render text: %v", err)
+		}
+		if !strings.Contains(b.String(), "^") {
+			t.Errorf("expected the early mix switch in the first column, got:\n%s", b.String())
+		}
+	})
+}
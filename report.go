@@ -0,0 +1,337 @@
+package uddf
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+)
+
+// Report holds the data of a printable logbook page for a dive. References
+// of the dive are resolved against its document.
+type Report struct {
+	Dive       *Dive
+	Number     int    // the dive number, or the position of the dive in the document
+	Diver      string // the name of the owner
+	Site       *Site
+	Buddies    []*Buddy
+	Equipment  []ReportEquipment
+	Tanks      []ReportTank
+	Mixes      []*Mix // the mixes breathed from tanks or switched to
+	Chart      *Chart
+	Notes      []string
+	Signatures []ReportSignature
+}
+
+// ReportEquipment is a piece of equipment used on a dive.
+type ReportEquipment struct {
+	Kind EquipmentKind
+	Part *EquipmentPart
+}
+
+// ReportTank is a tank used on a dive with the mix it was filled with.
+type ReportTank struct {
+	*TankData
+	Mix *Mix
+	// Used is the free gas consumed in cubic metres as computed by
+	// TankUsage, zero if the tank volume is unknown.
+	Used float64
+}
+
+// ReportSignature is a signature block. Fields of blocks that are to be
+// filled in by hand are empty.
+type ReportSignature struct {
+	Role              string // "Instructor" or "Buddy"
+	Name              string
+	Organization      string
+	Level             string
+	CertificateNumber string
+}
+
+// Report collects the logbook page of d. Instructors are taken from the
+// owner's certifications that link to the dive; without one an empty
+// instructor block is added. Every buddy gets a block of their own.
+func (u *UDDF) Report(d *Dive) *Report {
+	r := &Report{Dive: d, Chart: u.Chart(d)}
+	objects := objectsByID(u)
+
+	r.Diver = personalName(u.Diver.Owner.Personal)
+	for i, dive := range u.dives() {
+		if dive == d {
+			r.Number = i + 1
+		}
+	}
+	if n := d.InformationBeforeDive.DiveNumber; n != nil {
+		r.Number = *n
+	}
+
+	for _, l := range d.InformationBeforeDive.Links {
+		switch v := objects.get(l.Ref).(type) {
+		case *Site:
+			r.Site = v
+		case *Buddy:
+			r.Buddies = append(r.Buddies, v)
+		}
+	}
+
+	if eq := d.InformationAfterDive.EquipmentUsed; eq != nil {
+		inventory := u.Inventory()
+		for _, l := range eq.Links {
+			if item := inventory.Find(l.Ref); item != nil {
+				r.Equipment = append(r.Equipment, ReportEquipment{Kind: item.Kind, Part: item.Part})
+			}
+		}
+	}
+
+	seen := map[*Mix]bool{}
+	addMix := func(m *Mix) {
+		if m != nil && !seen[m] {
+			seen[m] = true
+			r.Mixes = append(r.Mixes, m)
+		}
+	}
+	for _, t := range u.TankUsage(d) {
+		addMix(t.Mix)
+		r.Tanks = append(r.Tanks, ReportTank{TankData: t.TankData, Mix: t.Mix, Used: t.Consumed})
+	}
+	if d.Samples != nil {
+		for _, w := range d.Samples.Waypoints {
+			if w.SwitchMix != nil {
+				m, _ := objects.get(w.SwitchMix.Ref).(*Mix)
+				addMix(m)
+			}
+		}
+	}
+
+	if notes := d.InformationAfterDive.Notes; notes != nil {
+		r.Notes = notes.Paras
+	}
+
+	if u.Diver.Owner.Education != nil {
+		for _, c := range u.Diver.Owner.Education.Certifications {
+			if c.Link == nil || c.Link.Ref != d.ID {
+				continue
+			}
+			s := certificationSignature("Instructor", c)
+			if c.Instructor != nil {
+				s.Name = personalName(c.Instructor.Personal)
+			}
+			r.Signatures = append(r.Signatures, s)
+		}
+	}
+	if len(r.Signatures) == 0 {
+		r.Signatures = append(r.Signatures, ReportSignature{Role: "Instructor"})
+	}
+	for _, b := range r.Buddies {
+		s := ReportSignature{Role: "Buddy"}
+		if b.Certification != nil {
+			s = certificationSignature("Buddy", *b.Certification)
+		}
+		s.Name = personalName(b.Personal)
+		r.Signatures = append(r.Signatures, s)
+	}
+	return r
+}
+
+func certificationSignature(role string, c Certification) ReportSignature {
+	s := ReportSignature{Role: role, Level: c.Level}
+	if c.Specialty != "" {
+		s.Level = strings.TrimSpace(c.Level + " " + c.Specialty)
+	}
+	if c.Organization != nil {
+		s.Organization = *c.Organization
+	}
+	if c.CertificateNumber != nil {
+		s.CertificateNumber = *c.CertificateNumber
+	}
+	return s
+}
+
+// objectIndex maps IDs to the nodes of the elements carrying them.
+type objectIndex map[string]*Node
+
+// objectsByID indexes every element of u that has an ID. The first one wins
+// if an ID is used twice.
+func objectsByID(u *UDDF) objectIndex {
+	objects := objectIndex{}
+	Walk(u, Visitor{Node: func(n *Node) WalkAction {
+		if id, ok := n.Value.(*string); ok && n.Name == "@id" && *id != "" && n.Parent != nil {
+			if _, dup := objects[*id]; !dup {
+				objects[*id] = n.Parent
+			}
+		}
+		return Continue
+	}})
+	return objects
+}
+
+// get returns a pointer to the element with the given ID, nil if there is
+// none.
+func (o objectIndex) get(id string) any {
+	if n, ok := o[id]; ok {
+		return n.Value
+	}
+	return nil
+}
+
+// dives returns pointers to all dives of u.
+func (u *UDDF) dives() []*Dive {
+	var result []*Dive
+	for g := range u.ProfileData.RepetitionGroup {
+		group := &u.ProfileData.RepetitionGroup[g]
+		for i := range group.Dives {
+			result = append(result, &group.Dives[i])
+		}
+	}
+	return result
+}
+
+func personalName(p Personal) string {
+	var parts []string
+	for _, s := range []*string{p.FirstName, p.LastName} {
+		if s != nil && *s != "" {
+			parts = append(parts, *s)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// ReportFuncs are the functions available to report templates:
+//
+//	celsius  K → °C        bar    Pa → bar
+//	litres   m³ → l        minutes s → min
+//	percent  fraction → %  date    Time → "2006-01-02 15:04", or less if less precise
+//	name     Personal → first and last name
+//	deref    *float64 → float64, zero if nil
+//	svg      Chart, width, height → inline SVG
+var ReportFuncs = template.FuncMap{
+	"celsius": celsius,
+	"bar":     func(pa float64) float64 { return pa / pascalPerBar },
+	"litres":  func(m3 float64) float64 { return m3 * 1000 },
+	"minutes": func(s float64) float64 { return s / 60 },
+	"percent": func(f float64) float64 { return f * 100 },
+	"date": func(t Time) string {
+		if time.Time(t).IsZero() {
+			return ""
+		}
+		return t.display()
+	},
+	"name":  personalName,
+	"deref": deref,
+	"svg": func(c *Chart, width, height int) (template.HTML, error) {
+		var b strings.Builder
+		if err := c.WriteSVG(&b, ChartOptions{Width: width, Height: height}); err != nil {
+			return "", err
+		}
+		return template.HTML(b.String()), nil
+	},
+}
+
+// ParseReportTemplate parses text as a report template with ReportFuncs.
+// The template is executed with a *Report.
+func ParseReportTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("report").Funcs(ReportFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse report template: %w", err)
+	}
+	return tmpl, nil
+}
+
+var defaultReportTemplate = template.Must(ParseReportTemplate(DefaultReportTemplate))
+
+// WriteHTML renders the report with tmpl, or with DefaultReportTemplate if
+// tmpl is nil.
+func (r *Report) WriteHTML(w io.Writer, tmpl *template.Template) error {
+	if tmpl == nil {
+		tmpl = defaultReportTemplate
+	}
+	if err := tmpl.Execute(w, r); err != nil {
+		return fmt.Errorf("failed to render report: %w", err)
+	}
+	return nil
+}
+
+// DefaultReportTemplate lays out a report as a single A4 page. It may serve
+// as a starting point for custom templates.
+const DefaultReportTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Dive {{.Number}}{{with .Site}} – {{.Name}}{{end}}</title>
+<style>
+@page { size: A4; margin: 15mm; }
+body { font-family: sans-serif; font-size: 10pt; color: #222; max-width: 180mm; margin: auto; }
+h1 { font-size: 16pt; margin-bottom: 0; }
+h2 { font-size: 11pt; border-bottom: 1px solid #999; margin: 1.2em 0 0.4em; }
+table { border-collapse: collapse; width: 100%; }
+th, td { text-align: left; padding: 2px 6px 2px 0; vertical-align: top; }
+.facts td:nth-child(odd) { color: #666; width: 18%; }
+.chart svg { width: 100%; height: auto; }
+.signatures { display: flex; gap: 8mm; flex-wrap: wrap; margin-top: 1em; }
+.signature { flex: 1; min-width: 55mm; }
+.line { border-bottom: 1px solid #222; height: 14mm; }
+.small { font-size: 8pt; color: #666; }
+</style>
+</head>
+<body>
+<h1>Dive {{.Number}}{{with .Site}} – {{.Name}}{{end}}</h1>
+<p class="small">{{.Diver}} · {{date .Dive.InformationBeforeDive.DateTime}}</p>
+
+<table class="facts">
+<tr><td>Date</td><td>{{date .Dive.InformationBeforeDive.DateTime}}</td><td>Dive time</td><td>{{printf "%.0f" (minutes .Dive.InformationAfterDive.DiveDuration)}} min</td></tr>
+<tr><td>Greatest depth</td><td>{{printf "%.1f" .Dive.InformationAfterDive.GreatestDepth}} m</td><td>Average depth</td><td>{{with .Dive.InformationAfterDive.AverageDepth}}{{printf "%.1f" (deref .)}} m{{end}}</td></tr>
+<tr><td>Lowest temperature</td><td>{{with .Dive.InformationAfterDive.LowestTemperature}}{{printf "%.1f" (celsius (deref .))}} °C{{end}}</td><td>Visibility</td><td>{{with .Dive.InformationAfterDive.Visibility}}{{with .Value}}{{printf "%.0f" (deref .)}} m{{end}}{{end}}</td></tr>
+{{- with .Site}}
+<tr><td>Site</td><td>{{.Name}}</td><td>Location</td><td>{{with .Geography}}{{.Location}}{{if and .Latitude .Longitude}} ({{printf "%.4f, %.4f" (deref .Latitude) (deref .Longitude)}}){{end}}{{end}}</td></tr>
+{{- end}}
+{{- with .Buddies}}
+<tr><td>Buddies</td><td colspan="3">{{range $i, $b := .}}{{if $i}}, {{end}}{{name $b.Personal}}{{end}}</td></tr>
+{{- end}}
+</table>
+
+{{- if .Chart.Waypoints}}
+<h2>Profile</h2>
+<div class="chart">{{svg .Chart 700 260}}</div>
+{{- end}}
+
+{{- if or .Tanks .Mixes}}
+<h2>Gases</h2>
+<table>
+<tr><th>Tank</th><th>Mix</th><th>Volume</th><th>Begin</th><th>End</th><th>Used</th></tr>
+{{- range .Tanks}}
+<tr><td>{{.ID}}</td><td>{{with .Mix}}{{.Name}}{{end}}</td><td>{{with .TankVolume}}{{printf "%.1f" (litres (deref .))}} l{{end}}</td><td>{{printf "%.0f" (bar .TankPressureBegin)}} bar</td><td>{{printf "%.0f" (bar .TankPressureEnd)}} bar</td><td>{{if .Used}}{{printf "%.0f" (litres .Used)}} l{{end}}</td></tr>
+{{- end}}
+</table>
+<p>{{range $i, $m := .Mixes}}{{if $i}} · {{end}}{{$m.Name}}: {{printf "%.0f" (percent (deref $m.O2))}}% O₂{{with $m.He}}, {{printf "%.0f" (percent (deref .))}}% He{{end}}{{end}}</p>
+{{- end}}
+
+{{- with .Equipment}}
+<h2>Equipment</h2>
+<table>
+{{- range .}}
+<tr><td>{{.Kind}}</td><td>{{.Part.Name}}</td><td>{{with .Part.Manufacturer}}{{.Name}}{{end}} {{with .Part.Model}}{{.}}{{end}}</td><td>{{with .Part.SerialNumber}}{{.}}{{end}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+
+{{- with .Notes}}
+<h2>Notes</h2>
+{{- range .}}
+<p>{{.}}</p>
+{{- end}}
+{{- end}}
+
+<h2>Signatures</h2>
+<div class="signatures">
+{{- range .Signatures}}
+<div class="signature">
+<div class="line"></div>
+<div>{{.Role}}{{with .Name}}: {{.}}{{end}}</div>
+<div class="small">{{with .Organization}}{{.}} {{end}}{{.Level}}{{with .CertificateNumber}} · No. {{.}}{{end}}</div>
+</div>
+{{- end}}
+</div>
+</body>
+</html>
+`
@@ -0,0 +1,94 @@
+package uddf
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReport(t *testing.T) {
+	u := buildDocument(t, func(b *Builder) {
+		b.AddBuddy("John", "Doe").
+			AddMix("Air", 0.21, 0).
+			AddMix("EAN50", 0.5, 0).
+			AddSite("Blue Hole", 17.3, -87.5).
+			AddDive(func(d *DiveBuilder) {
+				d.At(time.Date(2023, 8, 1, 9, 0, 0, 0, time.UTC)).Site("Blue Hole").Buddy("John Doe").
+					Tank("Air", 0.012, 200e5, 60e5).Notes("Deep training dive.", "Saw a <shark>.")
+				d.Sample(0, 0).Sample(20*time.Minute, 32).Sample(45*time.Minute, 0)
+				d.Dive().Samples.Waypoints[2].SwitchMix = &SwitchMix{Ref: "mix-ean50"}
+				d.Dive().InformationAfterDive.EquipmentUsed = &EquipmentUsed{Links: []Link{{Ref: "reg-1"}, {Ref: "mix-air"}}}
+			})
+	})
+	u.Diver.Owner.Equipment = &Equipment{}
+	u.Diver.Owner.Equipment.Regulators = []EquipmentPart{{Id: "reg-1", Name: "Primary", SerialNumber: ptr("R-42")}}
+	u.Diver.Buddies[0].Certification = &Certification{Level: "Divemaster", Organization: ptr("PADI"), CertificateNumber: ptr("DM-7")}
+	d := &u.ProfileData.RepetitionGroup[0].Dives[0]
+
+	t.Run("should resolve the references of the dive", func(t *testing.T) {
+		r := u.Report(d)
+		if r.Number != 1 || r.Diver != "Jane Diver" || r.Site == nil || r.Site.Name != "Blue Hole" {
+			t.Errorf("unexpected report %+v", r)
+		}
+		if len(r.Buddies) != 1 || len(r.Equipment) != 1 || r.Equipment[0].Kind != "regulator" {
+			t.Errorf("expected one buddy and one regulator, got %+v and %+v", r.Buddies, r.Equipment)
+		}
+		if len(r.Mixes) != 2 || r.Mixes[0].Name != "Air" || r.Mixes[1].Name != "EAN50" {
+			t.Errorf("expected air and EAN50, got %+v", r.Mixes)
+		}
+		// 1658 l for an ideal gas
+		if used := r.Tanks[0].Used * 1000; used < 1568.5 || used > 1569.5 {
+			t.Errorf("expected 1569 l used, got %.1f", used)
+		}
+		if len(r.Signatures) != 2 || r.Signatures[0] != (ReportSignature{Role: "Instructor"}) || r.Signatures[1].CertificateNumber != "DM-7" {
+			t.Errorf("unexpected signatures %+v", r.Signatures)
+		}
+	})
+
+	t.Run("instructors should be taken from the owner's certifications", func(t *testing.T) {
+		instructor := Instructor{}
+		instructor.Personal.FirstName, instructor.Personal.LastName = ptr("Ann"), ptr("Teach")
+		u.Diver.Owner.Education = &Education{Certifications: []Certification{
+			{Level: "Advanced", Specialty: "Deep", Instructor: &instructor, Link: &Link{Ref: d.ID}},
+			{Level: "Open Water", Link: &Link{Ref: "dive-0"}},
+		}}
+		defer func() { u.Diver.Owner.Education = nil }()
+
+		r := u.Report(d)
+		if s := r.Signatures[0]; s.Role != "Instructor" || s.Name != "Ann Teach" || s.Level != "Advanced Deep" {
+			t.Errorf("unexpected instructor %+v", s)
+		}
+	})
+
+	t.Run("HTML should contain every section", func(t *testing.T) {
+		var b bytes.Buffer
+		if err := u.Report(d).WriteHTML(&b, nil); err != nil {
+			t.Fatalf("failed to render report: %v", err)
+		}
+		html := b.String()
+		for _, want := range []string{"<title>Dive 1 – Blue Hole</title>", "John Doe", "<svg ", "EAN50: 50% O₂", "<td>regulator</td><td>Primary</td>", "R-42", "Saw a &lt;shark&gt;.", "200 bar", "1569 l", "Buddy: John Doe", "PADI Divemaster · No. DM-7"} {
+			if !strings.Contains(html, want) {
+				t.Errorf("expected report to contain %q", want)
+			}
+		}
+	})
+
+	t.Run("custom templates should be used", func(t *testing.T) {
+		tmpl, err := ParseReportTemplate(`{{.Site.Name}} {{printf "%.0f" (minutes .Dive.InformationAfterDive.DiveDuration)}}`)
+		if err != nil {
+			t.Fatalf("failed to parse template: %v", err)
+		}
+		var b bytes.Buffer
+		if err := u.Report(d).WriteHTML(&b, tmpl); err != nil {
+			t.Fatalf("failed to render report: %v", err)
+		}
+		if b.String() != "Blue Hole 45" {
+			t.Errorf("unexpected output %q", b.String())
+		}
+
+		if _, err := ParseReportTemplate("{{.Site"); err == nil {
+			t.Error("expected an error for an invalid template")
+		}
+	})
+}
@@ -0,0 +1,225 @@
+package uddf
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"time"
+)
+
+// SeriesOptions tune Series. Zero values select the default given in
+// brackets.
+type SeriesOptions struct {
+	// Model is the parameter set the dives are replayed with [the first one
+	// of the document's DecoModel, or ZHL16C].
+	Model *Buehlmann
+	// CNSHalfLife is the time in seconds it takes the CNS oxygen toxicity to
+	// halve at the surface [5400, i.e. 90 minutes].
+	CNSHalfLife float64
+}
+
+func (o SeriesOptions) withDefaults() SeriesOptions {
+	setDefault(&o.CNSHalfLife, 90*60)
+	return o
+}
+
+// maxRepetitiveInterval is the longest surface interval after which a dive
+// is still considered repetitive.
+const maxRepetitiveInterval = 48 * 3600
+
+// SeriesDive is a dive of a repetition group and the state it leaves the
+// diver in.
+type SeriesDive struct {
+	*Dive
+	Start time.Time
+	End   time.Time
+	// SurfaceInterval is the time in seconds spent at the surface before the
+	// dive: PassedTime if given, else derived from the timestamps. It is
+	// infinite for the first dive of the group and if Infinity is set.
+	SurfaceInterval float64
+	Derived         bool // SurfaceInterval was derived from the timestamps
+	Tissues         *TissueState
+	CNS             float64 // fraction of the CNS oxygen limit on surfacing
+}
+
+// Series is the repetitive dive series of a repetition group, in the order
+// the dives were made.
+type Series struct {
+	Group *RepetitionGroup
+	Dives []SeriesDive
+}
+
+// Series orders the dives of g by their start time and replays them one
+// after the other. Tissues and CNS oxygen toxicity are carried over the
+// surface intervals, breathing air at the surface of the following dive;
+// the first dive and dives after an infinite or unknown interval start
+// saturated at the surface and free of oxygen toxicity. Dives without a
+// timestamp keep their place in the document.
+func (u *UDDF) Series(g *RepetitionGroup, opts SeriesOptions) *Series {
+	if opts.Model == nil {
+		model := u.buehlmannFor(&Profile{})
+		opts.Model = &model
+	}
+	opts = opts.withDefaults()
+
+	s := &Series{Group: g, Dives: make([]SeriesDive, len(g.Dives))}
+	for i := range g.Dives {
+		d := &g.Dives[i]
+		s.Dives[i].Dive = d
+		s.Dives[i].Start, s.Dives[i].End = u.diveSpan(d)
+	}
+	// sort the timed dives among themselves, in the slots they occupy
+	var slots []int
+	var timed []SeriesDive
+	for i, sd := range s.Dives {
+		if !sd.Start.IsZero() {
+			slots = append(slots, i)
+			timed = append(timed, sd)
+		}
+	}
+	slices.SortStableFunc(timed, func(a, b SeriesDive) int { return a.Start.Compare(b.Start) })
+	for i, slot := range slots {
+		s.Dives[slot] = timed[i]
+	}
+
+	for i := range s.Dives {
+		sd := &s.Dives[i]
+		sd.SurfaceInterval = math.Inf(1)
+		var prev *SeriesDive
+		if i > 0 {
+			prev = &s.Dives[i-1]
+		}
+		before := sd.InformationBeforeDive.SurfaceIntervalBeforeDive
+		switch {
+		case prev == nil:
+		case before != nil && before.Infinity != nil && *before.Infinity:
+		case before != nil && before.PassedTime != nil:
+			sd.SurfaceInterval = *before.PassedTime
+		case !sd.Start.IsZero() && !prev.Start.IsZero():
+			sd.SurfaceInterval = math.Max(sd.Start.Sub(prev.End).Seconds(), 0)
+			sd.Derived = true
+		}
+
+		var start *TissueState
+		if prev != nil && !math.IsInf(sd.SurfaceInterval, 1) {
+			surface := u.Environment(sd.Dive).Surface()
+			start = prev.Tissues.Clone()
+			start.Expose(surface, surface, sd.SurfaceInterval, Air)
+			sd.CNS = prev.CNS * math.Pow(0.5, sd.SurfaceInterval/opts.CNSHalfLife)
+		}
+		sd.Tissues = u.Tissues(sd.Dive, opts.Model, start)
+		sd.CNS += u.CNS(sd.Dive)
+	}
+	return s
+}
+
+// cnsLimits are the NOAA single exposure limits in seconds for oxygen
+// partial pressures from 0.6 to 1.6 bar in steps of 0.1 bar.
+var cnsLimits = []float64{720 * 60, 570 * 60, 450 * 60, 360 * 60, 300 * 60, 240 * 60, 210 * 60, 180 * 60, 150 * 60, 120 * 60, 45 * 60}
+
+// cnsRate returns the fraction of the CNS oxygen limit used per second at
+// an oxygen partial pressure of po2 bar, interpolating the NOAA limits.
+// Partial pressures below 0.5 bar do not count; above 1.6 bar the rate of
+// 1.6 bar is used.
+func cnsRate(po2 float64) float64 {
+	if po2 <= 0.5 {
+		return 0
+	}
+	x := (po2 - 0.6) * 10
+	switch {
+	case x < 0:
+		// fade in between 0.5 and 0.6 bar
+		return (1 + x) / cnsLimits[0]
+	case x >= float64(len(cnsLimits)-1):
+		return 1 / cnsLimits[len(cnsLimits)-1]
+	}
+	i := int(x)
+	limit := cnsLimits[i] + (cnsLimits[i+1]-cnsLimits[i])*(x-float64(i))
+	return 1 / limit
+}
+
+// CNS returns the fraction of the CNS oxygen limit used during d, from the
+// oxygen partial pressure of the mix breathed between the samples. Mixes
+// follow the mix switches, air is breathed before the first one.
+func (u *UDDF) CNS(d *Dive) float64 {
+	if d.Samples == nil {
+		return 0
+	}
+	env := u.Environment(d)
+	gas := Air
+	var cns float64
+	for i, w := range d.Samples.Waypoints {
+		if i > 0 {
+			prev := d.Samples.Waypoints[i-1]
+			po2 := gas.O2 * (env.Pressure(prev.Depth) + env.Pressure(w.Depth)) / 2
+			cns += cnsRate(po2) * (w.DiveTime - prev.DiveTime)
+		}
+		if w.SwitchMix != nil {
+			if m := u.mix(w.SwitchMix.Ref); m != nil {
+				gas = m.Fractions()
+			}
+		}
+	}
+	return cns
+}
+
+// diveSpan returns the start and end of d, both zero if it has no
+// timestamp.
+func (u *UDDF) diveSpan(d *Dive) (start, end time.Time) {
+	if time.Time(d.InformationBeforeDive.DateTime).IsZero() {
+		return start, end
+	}
+	start = u.DiveTime(d, nil)
+	return start, start.Add(time.Duration(d.InformationAfterDive.DiveDuration * float64(time.Second)))
+}
+
+// seriesIssues checks that the dives of every repetition group follow one
+// another: in the order of the document, without overlapping, and with
+// recorded surface intervals matching the timestamps.
+func (u *UDDF) seriesIssues(add func(severity, path, format string, args ...any)) {
+	type timed struct {
+		path       string
+		dive       *Dive
+		start, end time.Time
+	}
+	for g := range u.ProfileData.RepetitionGroup {
+		group := &u.ProfileData.RepetitionGroup[g]
+		var dives []timed
+		for i := range group.Dives {
+			d := &group.Dives[i]
+			path := fmt.Sprintf("/uddf/profiledata/repetitiongroup[%d]/dive[%d]", g+1, i+1)
+			before := d.InformationBeforeDive.SurfaceIntervalBeforeDive
+			if i > 0 && before != nil && before.Infinity != nil && *before.Infinity {
+				add(SeverityWarning, path+"/informationbeforedive/surfaceintervalbeforedive",
+					"infinite surface interval within a repetition group")
+			}
+			start, end := u.diveSpan(d)
+			if start.IsZero() {
+				continue
+			}
+			if n := len(dives); n > 0 && start.Before(dives[n-1].start) {
+				add(SeverityWarning, path+"/informationbeforedive/datetime",
+					"dive starts before the preceding dive %q", dives[n-1].dive.ID)
+			}
+			dives = append(dives, timed{path, d, start, end})
+		}
+
+		slices.SortStableFunc(dives, func(a, b timed) int { return a.start.Compare(b.start) })
+		for i := 1; i < len(dives); i++ {
+			prev, d := dives[i-1], dives[i]
+			interval := d.start.Sub(prev.end).Seconds()
+			before := d.dive.InformationBeforeDive.SurfaceIntervalBeforeDive
+			switch {
+			case interval < 0:
+				add(SeverityError, d.path+"/informationbeforedive/datetime",
+					"dive starts %.0f min before the previous dive %q ends", -interval/60, prev.dive.ID)
+			case interval > maxRepetitiveInterval:
+				add(SeverityWarning, d.path+"/informationbeforedive/datetime",
+					"surface interval of %.0f h is too long for a repetitive dive", interval/3600)
+			case before != nil && before.PassedTime != nil && math.Abs(*before.PassedTime-interval) > 15*60:
+				add(SeverityWarning, d.path+"/informationbeforedive/surfaceintervalbeforedive/passedtime",
+					"surface interval of %.0f min does not match the %.0f min between the dives", *before.PassedTime/60, interval/60)
+			}
+		}
+	}
+}
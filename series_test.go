@@ -0,0 +1,135 @@
+package uddf
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSeries(t *testing.T) {
+	day := time.Date(2024, 9, 1, 0, 0, 0, 0, time.UTC)
+	dive := func(at time.Duration, depth float64) func(d *DiveBuilder) {
+		return func(d *DiveBuilder) {
+			d.At(day.Add(at)).Duration(40*time.Minute).Tank("Nitrox 32", 0.012, 200e5, 60e5)
+			d.Sample(0, 0).Sample(2*time.Minute, depth).Sample(35*time.Minute, depth).Sample(40*time.Minute, 0)
+		}
+	}
+	build := func(t *testing.T, dives ...func(d *DiveBuilder)) *UDDF {
+		return buildDocument(t, func(b *Builder) {
+			b.AddMix("Nitrox 32", 0.32, 0)
+			for _, d := range dives {
+				b.AddDive(d)
+			}
+		})
+	}
+
+	t.Run("dives should be ordered and carry their state", func(t *testing.T) {
+		u := build(t, dive(9*time.Hour, 25), dive(15*time.Hour, 20), dive(11*time.Hour, 25))
+		s := u.Series(&u.ProfileData.RepetitionGroup[0], SeriesOptions{})
+
+		var order []string
+		for _, sd := range s.Dives {
+			order = append(order, sd.ID)
+		}
+		if got := strings.Join(order, " "); got != "dive-1 dive-3 dive-2" {
+			t.Fatalf("expected dives in the order they were made, got %s", got)
+		}
+
+		first, second := s.Dives[0], s.Dives[1]
+		if !math.IsInf(first.SurfaceInterval, 1) || first.Derived {
+			t.Errorf("expected an infinite interval before the first dive, got %v", first.SurfaceInterval)
+		}
+		if second.SurfaceInterval != 80*60 || !second.Derived {
+			t.Errorf("expected an interval of 80 min derived from the timestamps, got %v", second.SurfaceInterval)
+		}
+
+		alone := u.Tissues(second.Dive, nil, nil)
+		if slowest := len(alone.N2) - 1; second.Tissues.N2[slowest] <= alone.N2[slowest] {
+			t.Error("expected the repetitive dive to load the tissues more than the same dive alone")
+		}
+		if own := u.CNS(second.Dive); second.CNS <= own || second.CNS >= own+first.CNS {
+			t.Errorf("expected residual CNS from the first dive, got %.3f for a dive of %.3f", second.CNS, own)
+		}
+	})
+
+	t.Run("untimed dives should keep their place", func(t *testing.T) {
+		u := build(t, dive(15*time.Hour, 20), dive(0, 20), dive(9*time.Hour, 25))
+		u.ProfileData.RepetitionGroup[0].Dives[1].InformationBeforeDive.DateTime = Time{}
+		s := u.Series(&u.ProfileData.RepetitionGroup[0], SeriesOptions{})
+
+		var order []string
+		for _, sd := range s.Dives {
+			order = append(order, sd.ID)
+		}
+		if got := strings.Join(order, " "); got != "dive-3 dive-2 dive-1" {
+			t.Fatalf("expected the timed dives to swap around the untimed one, got %s", got)
+		}
+		if !math.IsInf(s.Dives[2].SurfaceInterval, 1) {
+			t.Errorf("expected an unknown interval after the untimed dive, got %v", s.Dives[2].SurfaceInterval)
+		}
+	})
+
+	t.Run("recorded intervals should take precedence", func(t *testing.T) {
+		u := build(t, dive(9*time.Hour, 25), dive(11*time.Hour, 25))
+		before := &u.ProfileData.RepetitionGroup[0].Dives[1].InformationBeforeDive
+		before.SurfaceIntervalBeforeDive = &SurfaceIntervalBeforeDive{PassedTime: ptr(3600.0)}
+		g := &u.ProfileData.RepetitionGroup[0]
+
+		if sd := u.Series(g, SeriesOptions{}).Dives[1]; sd.SurfaceInterval != 3600 || sd.Derived {
+			t.Errorf("expected the recorded interval, got %v", sd.SurfaceInterval)
+		}
+
+		before.SurfaceIntervalBeforeDive = &SurfaceIntervalBeforeDive{Infinity: ptr(true)}
+		sd := u.Series(g, SeriesOptions{}).Dives[1]
+		if !math.IsInf(sd.SurfaceInterval, 1) || sd.CNS != u.CNS(sd.Dive) {
+			t.Errorf("expected a fresh start after an infinite interval, got %v", sd.SurfaceInterval)
+		}
+	})
+
+	t.Run("CNS should follow the NOAA limits", func(t *testing.T) {
+		for _, tc := range []struct{ po2, limit float64 }{{0.4, 0}, {1.0, 300}, {1.45, 135}, {1.6, 45}, {1.8, 45}} {
+			rate := cnsRate(tc.po2)
+			if tc.limit == 0 {
+				if rate != 0 {
+					t.Errorf("expected no CNS at %.2f bar, got %v", tc.po2, rate)
+				}
+				continue
+			}
+			if limit := 1 / rate / 60; math.Abs(limit-tc.limit) > 1e-6 {
+				t.Errorf("expected a limit of %.0f min at %.2f bar, got %.1f", tc.limit, tc.po2, limit)
+			}
+		}
+	})
+
+	t.Run("inconsistent groups should be reported", func(t *testing.T) {
+		u := build(t, dive(9*time.Hour, 25), dive(9*time.Hour+30*time.Minute, 20), dive(11*time.Hour, 20), dive(80*time.Hour, 20))
+		dives := u.ProfileData.RepetitionGroup[0].Dives
+		dives[2].InformationBeforeDive.SurfaceIntervalBeforeDive = &SurfaceIntervalBeforeDive{PassedTime: ptr(600.0)}
+		dives[3].InformationBeforeDive.SurfaceIntervalBeforeDive = &SurfaceIntervalBeforeDive{Infinity: ptr(true)}
+
+		var got []string
+		for _, issue := range u.Check() {
+			got = append(got, issue.Severity+" "+issue.Path+" "+issue.Message)
+		}
+		expected := []string{
+			"warning /uddf/profiledata/repetitiongroup[1]/dive[4]/informationbeforedive/surfaceintervalbeforedive infinite surface interval",
+			`error /uddf/profiledata/repetitiongroup[1]/dive[2]/informationbeforedive/datetime dive starts 10 min before the previous dive "dive-1" ends`,
+			"warning /uddf/profiledata/repetitiongroup[1]/dive[3]/informationbeforedive/surfaceintervalbeforedive/passedtime surface interval of 10 min does not match the 50 min",
+			"warning /uddf/profiledata/repetitiongroup[1]/dive[4]/informationbeforedive/datetime surface interval of 68 h is too long",
+		}
+		if len(got) != len(expected) {
+			t.Fatalf("expected %d issues, got %d:\n%s", len(expected), len(got), strings.Join(got, "\n"))
+		}
+		for i, want := range expected {
+			if !strings.HasPrefix(got[i], want) {
+				t.Errorf("issue %d: expected %q, got %q", i, want, got[i])
+			}
+		}
+
+		u = build(t, dive(11*time.Hour, 25), dive(9*time.Hour, 25))
+		if issues := u.Check(); len(issues) != 1 || !strings.Contains(issues[0].Message, `before the preceding dive "dive-1"`) {
+			t.Errorf("expected dives out of order to be reported, got %v", issues)
+		}
+	})
+}
@@ -0,0 +1,296 @@
+package uddf
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// DecoTable is a decompression table computed for a TableScope.
+type DecoTable struct {
+	Title    string
+	Altitude float64 // metres above sea level
+	MixRef   string  // bottom mix
+	Rows     []DecoTableRow
+}
+
+// DecoTableRow holds the entries of a single depth of a DecoTable.
+type DecoTableRow struct {
+	Depth      float64 // metres
+	NoDecoTime float64 // seconds from leaving the surface, capped at six hours
+	Entries    []DecoTableEntry
+}
+
+// DecoTableEntry is the ascent schedule for one bottom time at a depth.
+type DecoTableEntry struct {
+	BottomTime float64 // seconds from leaving the surface to leaving the bottom
+	DecoStops  []Decostop
+	AscentTime float64 // seconds from leaving the bottom to surfacing
+}
+
+// CalculateTables computes every table listed in
+// TableGeneration.CalculateTable.
+func (u *UDDF) CalculateTables() ([]*DecoTable, error) {
+	if u.TableGeneration.CalculateTable == nil {
+		return nil, nil
+	}
+
+	tables := make([]*DecoTable, 0, len(u.TableGeneration.CalculateTable.Tables))
+	for i := range u.TableGeneration.CalculateTable.Tables {
+		table, err := u.CalculateTable(&u.TableGeneration.CalculateTable.Tables[i])
+		if err != nil {
+			return nil, fmt.Errorf("table %d: %w", i+1, err)
+		}
+		tables = append(tables, table)
+	}
+	return tables, nil
+}
+
+// CalculateTable computes the decompression table described by table. The
+// Bühlmann parameter set is taken from the table's own DecoModel, from a
+// parameter set of the document's DecoModel referenced by one of the
+// table's links, or from the first one the document defines, in that order.
+// The bottom mix is the first mix referenced by a link (air if there is
+// none), decompression mixes are taken from the switches listed under
+// MixChange.Ascent.
+func (u *UDDF) CalculateTable(table *Table) (*DecoTable, error) {
+	depths, err := tableDepths(table.TableScope)
+	if err != nil {
+		return nil, err
+	}
+	times, err := tableBottomTimes(table.TableScope)
+	if err != nil {
+		return nil, err
+	}
+
+	params := u.buehlmannFor(&table.Profile)
+	mixes, err := u.profileMixes(&table.Profile)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := PlanOptions{}
+	if table.MaximumAscendingRate != nil {
+		opts.AscentRate = *table.MaximumAscendingRate
+	}
+	altitude := deref(table.TableScope.Altitude)
+	opts.Environment = Environment{Altitude: altitude, Density: deref(table.Density)}
+	opts = opts.withDefaults()
+
+	result := &DecoTable{Altitude: altitude, MixRef: mixes[0].ID}
+	if table.Title != nil {
+		result.Title = *table.Title
+	}
+
+	for _, depth := range depths {
+		noDecoTime, err := NoDecoLimit(depth, mixes[0], params, opts)
+		if err != nil {
+			return nil, fmt.Errorf("%.0f m: %w", depth, err)
+		}
+
+		row := DecoTableRow{Depth: depth, NoDecoTime: noDecoTime}
+		descent := depth / opts.DescentRate
+		for _, bottomTime := range times {
+			if bottomTime < descent {
+				continue
+			}
+
+			segment := Segment{Depth: depth, Duration: bottomTime - descent, MixRef: mixes[0].ID}
+			plan, err := PlanDive([]Segment{segment}, mixes, params, opts)
+			if err != nil {
+				return nil, fmt.Errorf("%.0f m for %.0f min: %w", depth, bottomTime/60, err)
+			}
+
+			row.Entries = append(row.Entries, DecoTableEntry{
+				BottomTime: bottomTime,
+				DecoStops:  plan.DecoStops,
+				AscentTime: plan.Runtime - bottomTime,
+			})
+		}
+		result.Rows = append(result.Rows, row)
+	}
+
+	return result, nil
+}
+
+func tableDepths(scope TableScope) ([]float64, error) {
+	if scope.DiveDepthBegin == nil || scope.DiveDepthEnd == nil {
+		return nil, fmt.Errorf("table scope needs divedepthbegin and divedepthend")
+	}
+	begin, end := *scope.DiveDepthBegin, *scope.DiveDepthEnd
+	step := 3.0
+	if scope.DiveDepthStep != nil {
+		step = *scope.DiveDepthStep
+	}
+	if begin <= 0 || end < begin || step <= 0 {
+		return nil, fmt.Errorf("invalid depth range %.1f-%.1f m in steps of %.1f m", begin, end, step)
+	}
+
+	var depths []float64
+	for depth := begin; depth <= end+1e-9; depth += step {
+		depths = append(depths, depth)
+	}
+	return depths, nil
+}
+
+// tableBottomTimes returns the bottom times of the scope. The step width
+// changes linearly from BottomTimeStepBegin at the minimum to
+// BottomTimeStepEnd at the maximum bottom time.
+func tableBottomTimes(scope TableScope) ([]float64, error) {
+	if scope.BottomTimeMaximum == nil || scope.BottomTimeStepBegin == nil {
+		return nil, fmt.Errorf("table scope needs bottomtimemaximum and bottomtimestepbegin")
+	}
+	minimum, maximum := deref(scope.BottomTimeMinimum), *scope.BottomTimeMaximum
+	stepBegin := *scope.BottomTimeStepBegin
+	stepEnd := stepBegin
+	if scope.BottomTimeStepEnd != nil {
+		stepEnd = *scope.BottomTimeStepEnd
+	}
+	if maximum < minimum || stepBegin <= 0 || stepEnd <= 0 {
+		return nil, fmt.Errorf("invalid bottom time range %.0f-%.0f s", minimum, maximum)
+	}
+
+	t := minimum
+	if t <= 0 {
+		t = stepBegin
+	}
+
+	var times []float64
+	for t <= maximum+1e-9 {
+		times = append(times, t)
+
+		step := stepBegin
+		if maximum > minimum {
+			step += (stepEnd - stepBegin) * (t - minimum) / (maximum - minimum)
+		}
+		t += step
+	}
+	return times, nil
+}
+
+// buehlmannFor returns the Bühlmann parameter set that applies to profile.
+func (u *UDDF) buehlmannFor(profile *Profile) Buehlmann {
+	if profile.DecoModel != nil && len(profile.DecoModel.Buehlmann) > 0 {
+		return profile.DecoModel.Buehlmann[0]
+	}
+	if u.DecoModel == nil || len(u.DecoModel.Buehlmann) == 0 {
+		return Buehlmann{}
+	}
+	for _, link := range profile.Links {
+		for _, b := range u.DecoModel.Buehlmann {
+			if b.ID == link.Ref {
+				return b
+			}
+		}
+	}
+	return u.DecoModel.Buehlmann[0]
+}
+
+// profileMixes returns the bottom mix of profile followed by its
+// decompression mixes.
+func (u *UDDF) profileMixes(profile *Profile) ([]Mix, error) {
+	bottom := Mix{ID: "air", Name: "Air"}
+	for _, link := range profile.Links {
+		if mix := u.mix(link.Ref); mix != nil {
+			bottom = *mix
+			break
+		}
+	}
+
+	mixes := []Mix{bottom}
+	for _, w := range profile.MixChange.Ascent.Waypoints {
+		if w.SwitchMix == nil {
+			continue
+		}
+		mix := u.mix(w.SwitchMix.Ref)
+		if mix == nil {
+			return nil, fmt.Errorf("unknown mix %q", w.SwitchMix.Ref)
+		}
+		mixes = append(mixes, *mix)
+	}
+	return mixes, nil
+}
+
+// mix returns the mix with the given ID from the gas definitions.
+func (u *UDDF) mix(id string) *Mix {
+	if u.GasDefinitions == nil {
+		return nil
+	}
+	for i := range u.GasDefinitions.Mixes {
+		if u.GasDefinitions.Mixes[i].ID == id {
+			return &u.GasDefinitions.Mixes[i]
+		}
+	}
+	return nil
+}
+
+// WriteText renders the table for printing, one block per depth.
+func (t *DecoTable) WriteText(w io.Writer) error {
+	var b strings.Builder
+	if t.Title != "" {
+		fmt.Fprintf(&b, "%s\n", t.Title)
+	}
+	fmt.Fprintf(&b, "Mix: %s  Altitude: %.0f m\n", t.MixRef, t.Altitude)
+
+	for _, row := range t.Rows {
+		fmt.Fprintf(&b, "\n%.0f m  no-deco %s\n", row.Depth, formatNoDecoTime(row.NoDecoTime))
+		b.WriteString("  Bottom  Ascent  Stops\n")
+		for _, e := range row.Entries {
+			stops := formatStops(e.DecoStops, "%.0fm/%.0f'")
+			if stops == "" {
+				stops = "-"
+			}
+			fmt.Fprintf(&b, "  %5.0f'  %5.0f'  %s\n", e.BottomTime/60, math.Ceil(e.AscentTime/60), stops)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteCSV writes the table as CSV with one line per bottom time. Depths are
+// given in metres, times in minutes and stops as depth/duration pairs.
+func (t *DecoTable) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"depth", "bottom_time", "no_deco_time", "ascent_time", "stops"}); err != nil {
+		return err
+	}
+
+	for _, row := range t.Rows {
+		for _, e := range row.Entries {
+			record := []string{
+				strconv.FormatFloat(row.Depth, 'f', -1, 64),
+				strconv.FormatFloat(e.BottomTime/60, 'f', -1, 64),
+				strconv.FormatFloat(row.NoDecoTime/60, 'f', 0, 64),
+				strconv.FormatFloat(math.Ceil(e.AscentTime/60), 'f', 0, 64),
+				formatStops(e.DecoStops, "%.0f/%.0f"),
+			}
+			if err := cw.Write(record); err != nil {
+				return err
+			}
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// formatStops formats each stop's depth in metres and duration in minutes
+// with format and joins them with spaces.
+func formatStops(stops []Decostop, format string) string {
+	parts := make([]string, len(stops))
+	for i, s := range stops {
+		parts[i] = fmt.Sprintf(format, s.DecoDepth, s.Duration/60)
+	}
+	return strings.Join(parts, " ")
+}
+
+func formatNoDecoTime(t float64) string {
+	if t >= maxNoDecoTime {
+		return "unlimited"
+	}
+	return fmt.Sprintf("%.0f min", t/60)
+}
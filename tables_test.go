@@ -0,0 +1,99 @@
+package uddf
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+)
+
+func TestCalculateTable(t *testing.T) {
+	scope := TableScope{
+		DiveDepthBegin:      ptr(18.0),
+		DiveDepthEnd:        ptr(36.0),
+		DiveDepthStep:       ptr(6.0),
+		BottomTimeMinimum:   ptr(600.0),
+		BottomTimeMaximum:   ptr(2400.0),
+		BottomTimeStepBegin: ptr(600.0),
+	}
+	uddf := &UDDF{
+		DecoModel: &DecoModel{Buehlmann: []Buehlmann{{ID: "gf", GradientFactorLow: ptr(0.4), GradientFactorHigh: ptr(0.85)}}},
+		TableGeneration: TableGeneration{
+			CalculateTable: &CalculateTable{Tables: []Table{{TableScope: scope}}},
+		},
+	}
+
+	tables, err := uddf.CalculateTables()
+	if err != nil {
+		t.Fatalf("failed to calculate tables: %v", err)
+	}
+	if len(tables) != 1 {
+		t.Fatalf("expected 1 table, got %d", len(tables))
+	}
+
+	table := tables[0]
+	if len(table.Rows) != 4 {
+		t.Fatalf("expected 4 depths, got %d", len(table.Rows))
+	}
+	for i, row := range table.Rows {
+		if len(row.Entries) != 4 {
+			t.Errorf("expected 4 bottom times at %.0f m, got %d", row.Depth, len(row.Entries))
+		}
+		if i > 0 && row.NoDecoTime >= table.Rows[i-1].NoDecoTime {
+			t.Errorf("expected no-deco time to decrease with depth, got %v at %.0f m", row.NoDecoTime, row.Depth)
+		}
+		for _, e := range row.Entries {
+			if e.BottomTime <= row.NoDecoTime && len(e.DecoStops) > 0 {
+				t.Errorf("expected no stops within the no-deco time at %.0f m for %.0f s", row.Depth, e.BottomTime)
+			}
+			if e.BottomTime > row.NoDecoTime && len(e.DecoStops) == 0 {
+				t.Errorf("expected stops beyond the no-deco time at %.0f m for %.0f s", row.Depth, e.BottomTime)
+			}
+		}
+	}
+
+	t.Run("altitude should shorten no-deco times", func(t *testing.T) {
+		altitudeTable := Table{TableScope: scope}
+		altitudeTable.TableScope.Altitude = ptr(2000.0)
+
+		result, err := uddf.CalculateTable(&altitudeTable)
+		if err != nil {
+			t.Fatalf("failed to calculate table: %v", err)
+		}
+		if result.Rows[0].NoDecoTime >= table.Rows[0].NoDecoTime {
+			t.Errorf("expected shorter no-deco time at altitude, got %v vs %v", result.Rows[0].NoDecoTime, table.Rows[0].NoDecoTime)
+		}
+	})
+
+	t.Run("should render CSV", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := table.WriteCSV(&buf); err != nil {
+			t.Fatalf("failed to write CSV: %v", err)
+		}
+
+		records, err := csv.NewReader(&buf).ReadAll()
+		if err != nil {
+			t.Fatalf("failed to read CSV: %v", err)
+		}
+		if len(records) != 17 {
+			t.Errorf("expected header and 16 records, got %d", len(records))
+		}
+	})
+
+	t.Run("should render text", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := table.WriteText(&buf); err != nil {
+			t.Fatalf("failed to write text: %v", err)
+		}
+		if !strings.Contains(buf.String(), "36 m  no-deco") {
+			t.Errorf("expected a block for 36 m, got:\n%s", buf.String())
+		}
+	})
+
+	t.Run("missing depth range should fail", func(t *testing.T) {
+		_, err := uddf.CalculateTable(&Table{TableScope: TableScope{BottomTimeMaximum: ptr(600.0), BottomTimeStepBegin: ptr(300.0)}})
+		if err == nil {
+			t.Error("expected error for missing depth range, got nil")
+		}
+	})
+}
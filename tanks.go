@@ -0,0 +1,209 @@
+package uddf
+
+import (
+	"math"
+	"sort"
+)
+
+// TankUsage is the gas accounting of one tank of a dive. Pressures are
+// given in Pa, gas volumes in m^3 at surface pressure.
+type TankUsage struct {
+	*TankData
+	Mix       *Mix    // nil if unknown, air is assumed then
+	Volume    float64 // water volume of the tank, zero if unknown
+	Pressures []TankSample
+
+	// Begin and End are TankPressureBegin and TankPressureEnd, or the first
+	// and last recorded pressure if these are not given.
+	Begin float64
+	End   float64
+	// BeginMismatch and EndMismatch are the differences between the first
+	// and last recorded pressure and TankPressureBegin and TankPressureEnd,
+	// zero if either is missing.
+	BeginMismatch float64
+	EndMismatch   float64
+
+	// Consumed and Remaining are the free gas taken from the tank and left
+	// in it, accounting for the compressibility of the mix. The tank is
+	// taken to be at 20 °C at the beginning and at the lowest water
+	// temperature at the end, if known. They are zero if the volume is
+	// unknown.
+	Consumed  float64
+	Remaining float64
+}
+
+// TankSample is a pressure recorded at a waypoint.
+type TankSample struct {
+	DiveTime float64
+	Pressure float64
+}
+
+// Tanks is the gas accounting of the tanks of a dive.
+type Tanks []TankUsage
+
+// TankUsage accounts for every tank listed in the tank data of d. Waypoint
+// tank pressures are matched by the ID of the tank data or of a tank it
+// links to; pressures without a reference belong to the only tank of a
+// single tank dive. The tank volume is taken from the tank data or the
+// linked tank.
+func (u *UDDF) TankUsage(d *Dive) Tanks {
+	objects := objectsByID(u)
+	tanks := make(Tanks, len(d.TankData))
+	byRef := map[string]int{}
+	for i := range d.TankData {
+		t := &tanks[i]
+		t.TankData = &d.TankData[i]
+		byRef[t.ID] = i
+		if t.TankVolume != nil {
+			t.Volume = *t.TankVolume
+		}
+		for _, l := range t.Links {
+			switch v := objects.get(l.Ref).(type) {
+			case *Mix:
+				t.Mix = v
+			case *Tank:
+				byRef[l.Ref] = i
+				if t.Volume == 0 && v.TankVolume != nil {
+					t.Volume = *v.TankVolume
+				}
+			}
+		}
+	}
+
+	if d.Samples != nil {
+		for _, w := range d.Samples.Waypoints {
+			for _, p := range w.TankPressures {
+				i, ok := 0, len(tanks) == 1
+				if p.Ref != nil {
+					i, ok = byRef[*p.Ref]
+				}
+				if ok {
+					tanks[i].Pressures = append(tanks[i].Pressures, TankSample{DiveTime: w.DiveTime, Pressure: p.Value})
+				}
+			}
+		}
+	}
+
+	// tanks are filled at room temperature and cool down in the water
+	water := deref(d.InformationAfterDive.LowestTemperature)
+	for i := range tanks {
+		t := &tanks[i]
+		t.Begin, t.End = t.TankPressureBegin, t.TankPressureEnd
+		if n := len(t.Pressures); n > 0 {
+			first, last := t.Pressures[0].Pressure, t.Pressures[n-1].Pressure
+			if t.Begin > 0 {
+				t.BeginMismatch = first - t.Begin
+			} else {
+				t.Begin = first
+			}
+			if t.End > 0 {
+				t.EndMismatch = last - t.End
+			} else {
+				t.End = last
+			}
+		}
+
+		f := mixFractions(t.Mix)
+		t.Remaining = f.FreeGasVolume(t.Volume, t.End, water)
+		t.Consumed = f.FreeGasVolume(t.Volume, t.Begin, 0) - t.Remaining
+	}
+	return tanks
+}
+
+// Find returns the tank with the given tank data ID, nil if there is none.
+func (tanks Tanks) Find(id string) *TankUsage {
+	for i := range tanks {
+		if tanks[i].ID == id {
+			return &tanks[i]
+		}
+	}
+	return nil
+}
+
+// Consumed returns the free gas taken from all tanks.
+func (tanks Tanks) Consumed() float64 {
+	var sum float64
+	for _, t := range tanks {
+		sum += t.Consumed
+	}
+	return sum
+}
+
+// PressureAt returns the pressure of the tank at the given dive time,
+// interpolated between the recorded pressures. It returns false outside the
+// recorded period.
+func (t *TankUsage) PressureAt(diveTime float64) (float64, bool) {
+	ps := t.Pressures
+	i := sort.Search(len(ps), func(i int) bool { return ps[i].DiveTime >= diveTime })
+	switch {
+	case i == len(ps):
+		return 0, false
+	case ps[i].DiveTime == diveTime:
+		return ps[i].Pressure, true
+	case i == 0:
+		return 0, false
+	}
+	a, b := ps[i-1], ps[i]
+	return a.Pressure + (b.Pressure-a.Pressure)*(diveTime-a.DiveTime)/(b.DiveTime-a.DiveTime), true
+}
+
+// BalanceSample compares the pressures of several tanks at a dive time.
+type BalanceSample struct {
+	DiveTime  float64
+	Pressures []float64 // in the order of the tanks compared
+	Spread    float64   // highest minus lowest pressure
+}
+
+// Balance compares the pressures of the tanks with the given IDs at every
+// dive time a pressure of one of them was recorded and all of them are
+// known, such as the two tanks of a sidemount diver. Without IDs the tanks
+// filled with the mix of the first tank are compared.
+func (tanks Tanks) Balance(ids ...string) []BalanceSample {
+	var compared []*TankUsage
+	if len(ids) == 0 && len(tanks) > 0 {
+		for i := range tanks {
+			if tanks[i].Mix == tanks[0].Mix {
+				compared = append(compared, &tanks[i])
+			}
+		}
+	}
+	for _, id := range ids {
+		if t := tanks.Find(id); t != nil {
+			compared = append(compared, t)
+		}
+	}
+	if len(compared) < 2 {
+		return nil
+	}
+
+	var times []float64
+	for _, t := range compared {
+		for _, p := range t.Pressures {
+			times = append(times, p.DiveTime)
+		}
+	}
+	sort.Float64s(times)
+
+	var balance []BalanceSample
+	for i, at := range times {
+		if i > 0 && at == times[i-1] {
+			continue
+		}
+		s := BalanceSample{DiveTime: at}
+		low, high := math.Inf(1), math.Inf(-1)
+		for _, t := range compared {
+			p, ok := t.PressureAt(at)
+			if !ok {
+				s.Pressures = nil
+				break
+			}
+			s.Pressures = append(s.Pressures, p)
+			low, high = math.Min(low, p), math.Max(high, p)
+		}
+		if s.Pressures != nil {
+			s.Spread = high - low
+			balance = append(balance, s)
+		}
+	}
+	return balance
+}
@@ -0,0 +1,93 @@
+package uddf
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestTankUsage(t *testing.T) {
+	u := buildDocument(t, func(b *Builder) {
+		b.AddMix("Air", 0.21, 0).
+			AddMix("EAN50", 0.5, 0).
+			AddDive(func(d *DiveBuilder) {
+				d.At(time.Date(2024, 7, 1, 9, 0, 0, 0, time.UTC)).
+					Tank("Air", 0.0111, 200e5, 70e5).
+					Tank("Air", 0.0111, 200e5, 0).
+					Tank("EAN50", 0.0055, 0, 0)
+				d.Sample(0, 0).Sample(10*time.Minute, 30).Sample(20*time.Minute, 30).Sample(30*time.Minute, 6).Sample(40*time.Minute, 0)
+			})
+	})
+	d := &u.ProfileData.RepetitionGroup[0].Dives[0]
+	ws := d.Samples.Waypoints
+	pressures := [][]float64{{202, 200, 0}, {180, 160, 0}, {130, 150, 0}, {100, 120, 200}, {80, 110, 150}}
+	for i := range ws {
+		for j, p := range pressures[i] {
+			if p > 0 {
+				ws[i].TankPressures = append(ws[i].TankPressures, TankPressure{Ref: ptr(d.TankData[j].ID), Value: p * 1e5})
+			}
+		}
+	}
+	tanks := u.TankUsage(d)
+
+	t.Run("should collect the pressures of every tank", func(t *testing.T) {
+		if len(tanks) != 3 || len(tanks[0].Pressures) != 5 || len(tanks[2].Pressures) != 2 {
+			t.Fatalf("unexpected tanks %+v", tanks)
+		}
+		if tanks[2].Mix == nil || tanks[2].Mix.Name != "EAN50" || tanks[2].Pressures[0] != (TankSample{DiveTime: 1800, Pressure: 200e5}) {
+			t.Errorf("unexpected stage %+v", tanks[2])
+		}
+	})
+
+	t.Run("should reconcile with the begin and end pressures", func(t *testing.T) {
+		left, right, stage := tanks[0], tanks[1], tanks[2]
+		if left.Begin != 200e5 || left.End != 70e5 || left.BeginMismatch != 2e5 || left.EndMismatch != 10e5 {
+			t.Errorf("unexpected left tank %+v", left)
+		}
+		if right.End != 110e5 || right.EndMismatch != 0 {
+			t.Errorf("unexpected right tank %+v", right)
+		}
+		if stage.Begin != 200e5 || stage.End != 150e5 {
+			t.Errorf("unexpected stage %+v", stage)
+		}
+	})
+
+	t.Run("should account for compressibility", func(t *testing.T) {
+		left := tanks[0]
+		ideal := 0.0111 * (200 - 70) / seaLevelPressure
+		if left.Consumed >= ideal || left.Consumed < ideal*0.9 {
+			t.Errorf("expected less than %v m^3 consumed, got %v", ideal, left.Consumed)
+		}
+		if total := tanks.Consumed(); math.Abs(total-(tanks[0].Consumed+tanks[1].Consumed+tanks[2].Consumed)) > 1e-12 {
+			t.Errorf("unexpected total %v", total)
+		}
+		if z := Air.Compressibility(300e5, 0); z < 1.10 || z > 1.12 {
+			t.Errorf("expected Z of air at 300 bar near 1.11, got %v", z)
+		}
+		if z := (GasFractions{O2: 0.18, He: 0.45, N2: 0.37}).Compressibility(300e5, 0); z <= Air.Compressibility(300e5, 0) {
+			t.Errorf("expected trimix to be less compressible than air, got %v", z)
+		}
+	})
+
+	t.Run("should compare sidemount tanks", func(t *testing.T) {
+		balance := tanks.Balance()
+		if len(balance) != 5 {
+			t.Fatalf("expected 5 samples, got %+v", balance)
+		}
+		if b := balance[2]; b.DiveTime != 1200 || b.Spread != 20e5 || b.Pressures[0] != 130e5 {
+			t.Errorf("unexpected sample %+v", b)
+		}
+		if stage := tanks.Balance("tank-2", "tank-3"); len(stage) != 2 || stage[0].Spread != 80e5 {
+			t.Errorf("unexpected balance %+v", stage)
+		}
+	})
+
+	t.Run("should interpolate pressures", func(t *testing.T) {
+		if p, ok := tanks[0].PressureAt(300); !ok || p != 191e5 {
+			t.Errorf("expected 191 bar, got %v", p)
+		}
+		if _, ok := tanks[2].PressureAt(300); ok {
+			t.Error("expected no pressure before the first sample")
+		}
+	})
+}
@@ -0,0 +1,77 @@
+package uddf
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestTimePrecision(t *testing.T) {
+	tests := []struct {
+		input     string
+		precision TimePrecision
+		expected  time.Time
+	}{
+		{"2019", PrecisionYear, time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"2019-03-04", PrecisionDate, time.Date(2019, 3, 4, 0, 0, 0, 0, time.UTC)},
+		{"2019-03-04T13:05", PrecisionMinute, time.Date(2019, 3, 4, 13, 5, 0, 0, time.UTC)},
+		{"2019-03-04T13:05:06", PrecisionSecond, time.Date(2019, 3, 4, 13, 5, 6, 0, time.UTC)},
+		{"2019-01-01T00:00:00Z", PrecisionOffset, time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"2019-01-01T00:00:00+00:00", PrecisionOffset, time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"2019-03-04T13:05:06.25+01:00", PrecisionOffset, time.Date(2019, 3, 4, 12, 5, 6, 250e6, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			tm, err := ParseTime(tt.input)
+			if err != nil {
+				t.Fatalf("failed to parse: %v", err)
+			}
+			if tm.Precision() != tt.precision {
+				t.Errorf("expected precision %s, got %s", tt.precision, tm.Precision())
+			}
+			if !time.Time(tm).Equal(tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, time.Time(tm))
+			}
+			if tm.String() != tt.input {
+				t.Errorf("expected %q to be written back as is, got %q", tt.input, tm.String())
+			}
+
+			data, err := json.Marshal(tm)
+			if err != nil {
+				t.Fatalf("failed to marshal: %v", err)
+			}
+			var roundTrip Time
+			if err := json.Unmarshal(data, &roundTrip); err != nil {
+				t.Fatalf("failed to unmarshal: %v", err)
+			}
+			if roundTrip.Precision() != tt.precision || roundTrip.String() != tt.input {
+				t.Errorf("expected %q after a JSON round trip, got %q", tt.input, roundTrip)
+			}
+		})
+	}
+
+	t.Run("WithPrecision should truncate", func(t *testing.T) {
+		tm, _ := ParseTime("2019-03-04T13:05:06")
+		for p, want := range map[TimePrecision]string{
+			PrecisionYear:   "2019",
+			PrecisionDate:   "2019-03-04",
+			PrecisionMinute: "2019-03-04T13:05",
+			PrecisionOffset: "2019-03-04T13:05:06Z",
+		} {
+			if got := tm.WithPrecision(p).String(); got != want {
+				t.Errorf("%s: expected %q, got %q", p, want, got)
+			}
+		}
+	})
+
+	t.Run("diffs should report changed precision", func(t *testing.T) {
+		year, _ := ParseTime("2019")
+		full, _ := ParseTime("2019-01-01T00:00:00Z")
+		a := &UDDF{Generator: &Generator{DateTime: &year}}
+		b := &UDDF{Generator: &Generator{DateTime: &full}}
+		if a.Equal(b, 0) {
+			t.Error("expected times of different precision to differ")
+		}
+	})
+}
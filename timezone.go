@@ -0,0 +1,50 @@
+package uddf
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Location returns the time zone of the site given by Geography.TimeZone,
+// nil if there is none.
+func (s *Site) Location() *time.Location {
+	if s.Geography == nil || s.Geography.TimeZone == nil {
+		return nil
+	}
+	offset := int(math.Round(*s.Geography.TimeZone * 3600))
+	sign := '+'
+	if offset < 0 {
+		sign = '-'
+	}
+	abs := max(offset, -offset)
+	return time.FixedZone(fmt.Sprintf("UTC%c%02d:%02d", sign, abs/3600, abs%3600/60), offset)
+}
+
+// DiveTime returns the start of d in the time zone of the site the dive
+// links to, or in def if the site has none. Wall-clock times are taken as
+// local time there. A nil def stands for UTC.
+func (u *UDDF) DiveTime(d *Dive, def *time.Location) time.Time {
+	loc := def
+	if site := u.diveSite(d); site != nil {
+		if l := site.Location(); l != nil {
+			loc = l
+		}
+	}
+	return d.InformationBeforeDive.DateTime.In(loc)
+}
+
+// diveSite returns the first site d links to.
+func (u *UDDF) diveSite(d *Dive) *Site {
+	if u.DiveSite == nil {
+		return nil
+	}
+	for _, l := range d.InformationBeforeDive.Links {
+		for i := range u.DiveSite.Sites {
+			if u.DiveSite.Sites[i].ID == l.Ref {
+				return &u.DiveSite.Sites[i]
+			}
+		}
+	}
+	return nil
+}
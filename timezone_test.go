@@ -0,0 +1,105 @@
+package uddf
+
+import (
+	"encoding/xml"
+	"testing"
+	"time"
+)
+
+func TestTimeZones(t *testing.T) {
+	t.Run("marshalling should keep the original offset", func(t *testing.T) {
+		tests := []struct {
+			input, expected string
+			hasOffset       bool
+		}{
+			{"2023-06-21T13:05:30+02:00", "2023-06-21T13:05:30+02:00", true},
+			{"2023-06-21T13:05:30Z", "2023-06-21T13:05:30Z", true},
+			{"2023-06-21T13:05:30", "2023-06-21T13:05:30", false},
+			{"2023-06-21T13:05:30+00:00", "2023-06-21T13:05:30+00:00", true},
+		}
+		for _, tt := range tests {
+			tm, err := ParseTime(tt.input)
+			if err != nil {
+				t.Fatalf("failed to parse %q: %v", tt.input, err)
+			}
+			if tm.HasOffset() != tt.hasOffset {
+				t.Errorf("%s: expected HasOffset %v", tt.input, tt.hasOffset)
+			}
+			data, err := xml.Marshal(struct {
+				XMLName xml.Name `xml:"t"`
+				Time    Time     `xml:"datetime"`
+			}{Time: tm})
+			if err != nil {
+				t.Fatalf("failed to marshal: %v", err)
+			}
+			if want := "<t><datetime>" + tt.expected + "</datetime></t>"; string(data) != want {
+				t.Errorf("expected %s, got %s", want, data)
+			}
+		}
+	})
+
+	t.Run("wall-clock times should be resolved in the given zone", func(t *testing.T) {
+		cairo := time.FixedZone("EET", 2*3600)
+		wall, _ := ParseTime("2023-08-01T09:00:00")
+		if got := wall.UTC(cairo); !got.Equal(time.Date(2023, 8, 1, 7, 0, 0, 0, time.UTC)) {
+			t.Errorf("expected 07:00 UTC, got %v", got)
+		}
+		if got := wall.In(nil); !got.Equal(time.Date(2023, 8, 1, 9, 0, 0, 0, time.UTC)) {
+			t.Errorf("expected 09:00 UTC, got %v", got)
+		}
+
+		fixed, _ := ParseTime("2023-08-01T09:00:00+01:00")
+		if got := fixed.In(cairo); got.Hour() != 10 || got.Location() != cairo {
+			t.Errorf("expected 10:00 in Cairo, got %v", got)
+		}
+
+		if got := WallClockTime(fixed.In(cairo)); got.HasOffset() || got.String() != "2023-08-01T10:00:00" {
+			t.Errorf("expected wall clock 10:00, got %s", got)
+		}
+	})
+
+	t.Run("dives should use the time zone of their site", func(t *testing.T) {
+		u := buildDocument(t, func(b *Builder) {
+			b.AddSite("Brothers", 26.3, 34.8).
+				AddSite("Blue Hole", 17.3, -87.5).
+				AddDive(func(d *DiveBuilder) {
+					d.At(time.Time(WallClockTime(time.Date(2023, 8, 1, 9, 0, 0, 0, time.UTC)))).Site("Brothers")
+				}).
+				AddDive(func(d *DiveBuilder) {
+					d.At(time.Time(WallClockTime(time.Date(2023, 8, 1, 9, 0, 0, 0, time.UTC)))).Site("Blue Hole")
+				})
+		})
+		u.DiveSite.Sites[0].Geography.TimeZone = ptr(2.0)
+		u.DiveSite.Sites[1].Geography.TimeZone = ptr(-5.5)
+
+		if loc := u.DiveSite.Sites[1].Location(); loc.String() != "UTC-05:30" {
+			t.Errorf("expected UTC-05:30, got %s", loc)
+		}
+
+		dives := u.ProfileData.RepetitionGroup[0].Dives
+		if got := u.DiveTime(&dives[0], nil); got.UTC().Hour() != 7 || got.Hour() != 9 {
+			t.Errorf("expected 09:00 local and 07:00 UTC, got %v", got)
+		}
+		if got := u.DiveTime(&dives[1], nil).UTC(); got.Hour() != 14 || got.Minute() != 30 {
+			t.Errorf("expected 14:30 UTC, got %v", got)
+		}
+
+		u.DiveSite.Sites[0].Geography.TimeZone = nil
+		berlin := time.FixedZone("CEST", 2*3600)
+		if got := u.DiveTime(&dives[0], berlin); got.Location() != berlin || got.UTC().Hour() != 7 {
+			t.Errorf("expected the default zone, got %v", got)
+		}
+
+		data, err := Marshal(u)
+		if err != nil {
+			t.Fatalf("failed to marshal document: %v", err)
+		}
+		roundTrip, err := Parse(data)
+		if err != nil {
+			t.Fatalf("failed to parse document: %v", err)
+		}
+		if dt := roundTrip.ProfileData.RepetitionGroup[0].Dives[0].InformationBeforeDive.DateTime; dt.HasOffset() || dt.String() != "2023-08-01T09:00:00" {
+			t.Errorf("expected the wall-clock time to survive a round trip, got %s", dt)
+		}
+	})
+}
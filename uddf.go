@@ -1,19 +1,24 @@
 package uddf
 
 import (
-	"bytes"
 	"encoding/xml"
 	"fmt"
+	"io"
 	"os"
 
 	"github.com/go-playground/validator/v10"
 )
 
 func Parse(data []byte) (*UDDF, error) {
+	data, err := unpack(data)
+	if err != nil {
+		return nil, err
+	}
+
 	var uddf UDDF
-	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder := newDecoder(data)
 	if err := decoder.Decode(&uddf); err != nil {
-		return nil, fmt.Errorf("failed to decode UDDF file: %w", err)
+		return nil, newParseError(data, decoder, err)
 	}
 
 	return &uddf, nil
@@ -27,7 +32,39 @@ func ParseFile(filename string) (*UDDF, error) {
 	return Parse(data)
 }
 
-//func ParseReader(r io.Reader) (*UDDF, error)
+func ParseReader(r io.Reader) (*UDDF, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read UDDF data: %w", err)
+	}
+	return Parse(data)
+}
+
+// Marshal encodes u as an indented UDDF document including the XML header.
+func Marshal(u *UDDF) ([]byte, error) {
+	if u == nil {
+		return nil, fmt.Errorf("UDDF object is nil")
+	}
+
+	data, err := xml.MarshalIndent(u, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode UDDF file: %w", err)
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+// WriteFile writes u to filename as an uncompressed UDDF document, see
+// Marshal.
+func WriteFile(filename string, u *UDDF) error {
+	data, err := Marshal(u)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filename, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write file %s: %w", filename, err)
+	}
+	return nil
+}
 
 // Validate validates the UDDF structure using the validation tags defined in the structs
 func (u *UDDF) Validate() error {
@@ -38,3 +75,14 @@ func (u *UDDF) Validate() error {
 	validate := validator.New()
 	return validate.Struct(u)
 }
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func deref(v *float64) float64 {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
@@ -2,6 +2,8 @@ package uddf
 
 import (
 	"encoding/xml"
+	"errors"
+	"strings"
 	"testing"
 	"time"
 )
@@ -170,4 +172,157 @@ func TestDateOfTripXML(t *testing.T) {
 	if !actualEnd.Equal(expectedEnd) {
 		t.Errorf("expected end date %v, got %v", expectedEnd, actualEnd)
 	}
-}
\ No newline at end of file
+}
+
+func TestParseError(t *testing.T) {
+	t.Run("invalid datetime should report position and path", func(t *testing.T) {
+		_, err := ParseFile("testdata/invalid_datetime.uddf")
+		if err == nil {
+			t.Fatal("expected error for invalid datetime, got nil")
+		}
+
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) {
+			t.Fatalf("expected *ParseError, got %T", err)
+		}
+
+		if parseErr.Line != 16 {
+			t.Errorf("expected line 16, got %d", parseErr.Line)
+		}
+		if parseErr.RawValue != "15.01.2024 10:00" {
+			t.Errorf("expected raw value '15.01.2024 10:00', got '%s'", parseErr.RawValue)
+		}
+		expectedPath := "/uddf/profiledata/repetitiongroup/dive/informationbeforedive/datetime"
+		if parseErr.Path != expectedPath {
+			t.Errorf("expected path '%s', got '%s'", expectedPath, parseErr.Path)
+		}
+	})
+
+	t.Run("invalid number should report raw value", func(t *testing.T) {
+		data := "<uddf>\n<profiledata>\n<repetitiongroup>\n<dive>\n<informationafterdive>\n<greatestdepth>deep</greatestdepth>\n</informationafterdive>\n</dive>\n</repetitiongroup>\n</profiledata>\n</uddf>"
+		_, err := Parse([]byte(data))
+
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) {
+			t.Fatalf("expected *ParseError, got %T", err)
+		}
+
+		if parseErr.Line != 6 {
+			t.Errorf("expected line 6, got %d", parseErr.Line)
+		}
+		if parseErr.RawValue != "deep" {
+			t.Errorf("expected raw value 'deep', got '%s'", parseErr.RawValue)
+		}
+		if !strings.HasSuffix(parseErr.Path, "/informationafterdive/greatestdepth") {
+			t.Errorf("unexpected path '%s'", parseErr.Path)
+		}
+	})
+
+	t.Run("invalid attribute datetime should include attribute in path", func(t *testing.T) {
+		data := "<uddf>\n<divetrip>\n<trip>\n<trippart>\n<dateoftrip startdate=\"soon\" enddate=\"2003-04-19\"/>\n</trippart>\n</trip>\n</divetrip>\n</uddf>"
+		_, err := Parse([]byte(data))
+
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) {
+			t.Fatalf("expected *ParseError, got %T", err)
+		}
+
+		if parseErr.Path != "/uddf/divetrip/trip/trippart/dateoftrip/@startdate" {
+			t.Errorf("unexpected path '%s'", parseErr.Path)
+		}
+	})
+
+	t.Run("syntax error should report line", func(t *testing.T) {
+		data := "<uddf>\n<diver>\n<owner>\n</diver>\n</uddf>"
+		_, err := Parse([]byte(data))
+
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) {
+			t.Fatalf("expected *ParseError, got %T", err)
+		}
+
+		var syntaxErr *xml.SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("expected wrapped *xml.SyntaxError, got %T", parseErr.Err)
+		}
+		if parseErr.Line != 4 {
+			t.Errorf("expected line 4, got %d", parseErr.Line)
+		}
+	})
+}
+
+func TestParseEncodings(t *testing.T) {
+	files := []string{
+		"testdata/latin1.uddf",
+		"testdata/utf16.uddf",
+		"testdata/utf8_bom.uddf",
+	}
+
+	for _, file := range files {
+		t.Run(file, func(t *testing.T) {
+			uddf, err := ParseFile(file)
+			if err != nil {
+				t.Fatalf("failed to parse UDDF file: %v", err)
+			}
+
+			lastName := uddf.Diver.Owner.Personal.LastName
+			if lastName == nil || *lastName != "Müller" {
+				t.Errorf("expected last name 'Müller', got %v", lastName)
+			}
+
+			if len(uddf.ProfileData.RepetitionGroup) == 0 {
+				t.Error("expected at least one repetition group")
+			}
+		})
+	}
+
+	t.Run("unsupported encoding should fail", func(t *testing.T) {
+		data := `<?xml version="1.0" encoding="x-unknown"?><uddf version="3.2.3"></uddf>`
+		_, err := Parse([]byte(data))
+		if err == nil {
+			t.Error("expected error for unsupported encoding, got nil")
+		}
+	})
+}
+
+func TestParseReader(t *testing.T) {
+	uddf, err := ParseReader(strings.NewReader(`<uddf version="3.2.3"></uddf>`))
+	if err != nil {
+		t.Fatalf("failed to parse UDDF data: %v", err)
+	}
+
+	if uddf.Version != "3.2.3" {
+		t.Errorf("expected version '3.2.3', got '%s'", uddf.Version)
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	uddf, err := ParseFile("testdata/valid.uddf")
+	if err != nil {
+		t.Fatalf("failed to parse valid UDDF file: %v", err)
+	}
+
+	data, err := Marshal(uddf)
+	if err != nil {
+		t.Fatalf("failed to marshal UDDF: %v", err)
+	}
+
+	roundTrip, err := Parse(data)
+	if err != nil {
+		t.Fatalf("failed to parse marshalled UDDF: %v", err)
+	}
+
+	if err := roundTrip.Validate(); err != nil {
+		t.Errorf("expected no validation errors after round trip, got: %v", err)
+	}
+
+	expected := time.Time(uddf.ProfileData.RepetitionGroup[0].Dives[0].InformationBeforeDive.DateTime)
+	actual := time.Time(roundTrip.ProfileData.RepetitionGroup[0].Dives[0].InformationBeforeDive.DateTime)
+	if !actual.Equal(expected) {
+		t.Errorf("expected datetime %v, got %v", expected, actual)
+	}
+
+	if roundTrip.XMLName.Local != "uddf" {
+		t.Errorf("expected root element 'uddf', got '%s'", roundTrip.XMLName.Local)
+	}
+}
@@ -0,0 +1,185 @@
+package uddf
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Node is a value visited by Walk.
+type Node struct {
+	Path   string // XML path, e.g. /uddf/profiledata/repetitiongroup[1]/dive[2]/@id
+	Name   string // element name, "@name" for attributes, "" for character data
+	Value  any    // pointer to the value in the document, e.g. *Dive or *float64
+	Parent *Node  // nil for the root
+}
+
+// WalkAction tells Walk how to continue after visiting a node. If several
+// hooks return different actions for the same node, the last one in this
+// list wins.
+type WalkAction int
+
+const (
+	// Continue visits the children of the node.
+	Continue WalkAction = iota
+	// SkipChildren does not descend into the node.
+	SkipChildren
+	// Remove deletes the node from its parent: slice elements are dropped,
+	// other values are reset to their zero value.
+	Remove
+	// Stop ends the walk.
+	Stop
+)
+
+// Visitor holds the hooks called by Walk. Node is called for every node,
+// the typed hooks only for nodes of their type. Nil hooks are skipped. The
+// values may be changed in place.
+type Visitor struct {
+	Node            func(n *Node) WalkAction
+	OnDive          func(n *Node, d *Dive) WalkAction
+	OnSite          func(n *Node, s *Site) WalkAction
+	OnMix           func(n *Node, m *Mix) WalkAction
+	OnWaypoint      func(n *Node, w *Waypoint) WalkAction
+	OnEquipmentPart func(n *Node, p *EquipmentPart) WalkAction
+	OnLink          func(n *Node, l *Link) WalkAction
+	OnNotes         func(n *Node, notes *Notes) WalkAction
+	OnPrice         func(n *Node, p *Price) WalkAction
+}
+
+// Walk visits every element and attribute of u in document order, parents
+// before their children. Missing optional elements are not visited.
+func Walk(u *UDDF, visitor Visitor) {
+	if u == nil {
+		return
+	}
+	w := walker{visitor: visitor}
+	w.visit(&Node{Path: "/uddf", Name: "uddf", Value: u}, reflect.ValueOf(u).Elem())
+}
+
+type walker struct {
+	visitor Visitor
+	stopped bool
+}
+
+// visit calls the hooks for n, whose value is the addressable v, and walks
+// its children unless told otherwise.
+func (w *walker) visit(n *Node, v reflect.Value) WalkAction {
+	action := w.hooks(n)
+	switch action {
+	case Stop:
+		w.stopped = true
+	case Continue:
+		w.children(n, v)
+	}
+	return action
+}
+
+func (w *walker) hooks(n *Node) WalkAction {
+	action := Continue
+	call := func(a WalkAction) {
+		action = max(action, a)
+	}
+
+	v := w.visitor
+	if v.Node != nil {
+		call(v.Node(n))
+	}
+	switch x := n.Value.(type) {
+	case *Dive:
+		if v.OnDive != nil {
+			call(v.OnDive(n, x))
+		}
+	case *Site:
+		if v.OnSite != nil {
+			call(v.OnSite(n, x))
+		}
+	case *Mix:
+		if v.OnMix != nil {
+			call(v.OnMix(n, x))
+		}
+	case *Waypoint:
+		if v.OnWaypoint != nil {
+			call(v.OnWaypoint(n, x))
+		}
+	case *EquipmentPart:
+		if v.OnEquipmentPart != nil {
+			call(v.OnEquipmentPart(n, x))
+		}
+	case *Link:
+		if v.OnLink != nil {
+			call(v.OnLink(n, x))
+		}
+	case *Notes:
+		if v.OnNotes != nil {
+			call(v.OnNotes(n, x))
+		}
+	case *Price:
+		if v.OnPrice != nil {
+			call(v.OnPrice(n, x))
+		}
+	}
+	return action
+}
+
+func (w *walker) children(n *Node, v reflect.Value) {
+	if v.Kind() != reflect.Struct || v.Type() == timeType || v.Type() == flexibleFloatType {
+		return
+	}
+
+	for i := range v.NumField() {
+		if w.stopped {
+			return
+		}
+
+		field := v.Type().Field(i)
+		if !field.IsExported() || field.Name == "XMLName" {
+			continue
+		}
+		if field.Anonymous {
+			w.children(n, v.Field(i))
+			continue
+		}
+		if name, ok := xmlFieldName(field); ok {
+			w.field(n, name, v.Field(i))
+		}
+	}
+}
+
+// field visits the value of a struct field, removing it if asked to.
+func (w *walker) field(parent *Node, name string, v reflect.Value) {
+	path := parent.Path
+	if name != "" {
+		path += "/" + name
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return
+		}
+		n := &Node{Path: path, Name: name, Value: v.Interface(), Parent: parent}
+		if w.visit(n, v.Elem()) == Remove {
+			v.SetZero()
+		}
+	case reflect.Slice:
+		kept := 0
+		for i := range v.Len() {
+			elem := v.Index(i)
+			if !w.stopped {
+				n := &Node{Path: fmt.Sprintf("%s[%d]", path, i+1), Name: name, Value: elem.Addr().Interface(), Parent: parent}
+				if w.visit(n, elem) == Remove {
+					continue
+				}
+			}
+			v.Index(kept).Set(elem)
+			kept++
+		}
+		if kept < v.Len() {
+			v.Set(v.Slice(0, kept))
+		}
+	default:
+		n := &Node{Path: path, Name: name, Value: v.Addr().Interface(), Parent: parent}
+		if w.visit(n, v) == Remove {
+			v.SetZero()
+		}
+	}
+}
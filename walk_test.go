@@ -0,0 +1,98 @@
+package uddf
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWalk(t *testing.T) {
+	setup := func(b *Builder) {
+		b.AddBuddy("John", "Buddy").
+			AddMix("Air", 0.21, 0).
+			AddSite("Reef", 1, 2).
+			AddDive(func(d *DiveBuilder) {
+				d.Site("Reef").Buddy("John Buddy").Tank("Air", 0.012, 200e5, 50e5).Notes("Turtle")
+				d.Sample(0, 0).Sample(10*time.Minute, 12).Sample(20*time.Minute, 25).Sample(40*time.Minute, 0)
+			})
+	}
+
+	t.Run("hooks should see nodes with paths and parents", func(t *testing.T) {
+		u := buildDocument(t, setup)
+
+		var links []string
+		var waypoints int
+		var depthPath string
+		Walk(u, Visitor{
+			OnLink: func(n *Node, l *Link) WalkAction {
+				links = append(links, l.Ref)
+				return Continue
+			},
+			OnWaypoint: func(n *Node, w *Waypoint) WalkAction {
+				waypoints++
+				if _, ok := n.Parent.Value.(*Samples); !ok {
+					t.Errorf("expected waypoint parent to be *Samples, got %T", n.Parent.Value)
+				}
+				return Continue
+			},
+			Node: func(n *Node) WalkAction {
+				if _, ok := n.Value.(*float64); ok && n.Name == "greatestdepth" {
+					depthPath = n.Path
+				}
+				return Continue
+			},
+		})
+
+		if len(links) != 3 {
+			t.Errorf("expected 3 links, got %v", links)
+		}
+		if waypoints != 4 {
+			t.Errorf("expected 4 waypoints, got %d", waypoints)
+		}
+		if want := "/uddf/profiledata/repetitiongroup[1]/dive[1]/informationafterdive/greatestdepth"; depthPath != want {
+			t.Errorf("expected path %s, got %s", want, depthPath)
+		}
+	})
+
+	t.Run("hooks should mutate and prune in place", func(t *testing.T) {
+		u := buildDocument(t, setup)
+
+		Walk(u, Visitor{
+			OnWaypoint: func(n *Node, w *Waypoint) WalkAction {
+				if w.Depth > 20 {
+					return Remove
+				}
+				w.Depth += 1
+				return Continue
+			},
+			OnNotes: func(n *Node, notes *Notes) WalkAction {
+				return Remove
+			},
+		})
+
+		dive := u.ProfileData.RepetitionGroup[0].Dives[0]
+		if got := len(dive.Samples.Waypoints); got != 3 {
+			t.Fatalf("expected 3 waypoints after pruning, got %d", got)
+		}
+		if dive.Samples.Waypoints[1].Depth != 13 {
+			t.Errorf("expected mutated depth 13, got %v", dive.Samples.Waypoints[1].Depth)
+		}
+		if dive.InformationAfterDive.Notes != nil {
+			t.Error("expected notes to be removed")
+		}
+	})
+
+	t.Run("stop should end the walk", func(t *testing.T) {
+		u := buildDocument(t, setup)
+
+		visited := 0
+		Walk(u, Visitor{
+			OnLink: func(n *Node, l *Link) WalkAction {
+				visited++
+				return Stop
+			},
+		})
+		if visited != 1 {
+			t.Errorf("expected walk to stop after the first link, visited %d", visited)
+		}
+	})
+}